@@ -1,6 +1,7 @@
 const express = require('express');
 const puppeteer = require('puppeteer-extra');
 const StealthPlugin = require('puppeteer-extra-plugin-stealth');
+const { createGuard, interceptRequests } = require('./ssrf');
 
 puppeteer.use(StealthPlugin());
 
@@ -8,20 +9,38 @@ const app = express();
 app.use(express.json());
 
 app.post('/render', async (req, res) => {
-    const { url } = req.body;
+    const { url, userAgent, headers, timeoutMs, authorization, cookies, proxy } = req.body;
     if (!url) return res.status(400).send('Missing URL');
 
     let browser;
     try {
+        // Chrome takes the proxy without credentials; those are answered via page.authenticate
+        const args = ['--no-sandbox', '--disable-setuid-sandbox'];
+        let proxyAuth = null;
+        if (proxy) {
+            const proxyUrl = new URL(proxy);
+            if (proxyUrl.username) {
+                proxyAuth = {
+                    username: decodeURIComponent(proxyUrl.username),
+                    password: decodeURIComponent(proxyUrl.password),
+                };
+            }
+            args.push(`--proxy-server=${proxyUrl.protocol}//${proxyUrl.host}`);
+        }
+
         browser = await puppeteer.launch({
             headless: true,
-            args: ['--no-sandbox', '--disable-setuid-sandbox']
+            args,
         });
 
         const page = await browser.newPage();
+        if (proxyAuth) {
+            await page.authenticate(proxyAuth);
+        }
 
         await page.setUserAgent(
-            'Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120 Safari/537.36'
+            userAgent ||
+                'Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120 Safari/537.36'
         );
         await page.setExtraHTTPHeaders({
             'Accept-Language': 'en-US,en;q=0.9',
@@ -29,23 +48,65 @@ app.post('/render', async (req, res) => {
 
         await page.setViewport({ width: 1366, height: 768 });
 
+        // Credentials and custom headers are scoped to the target's origin and
+        // never sent to third parties
+        const targetOrigin = new URL(url).origin;
+        if (cookies && cookies.length) {
+            await page.setCookie(...cookies.map(({ name, value }) => ({ name, value, url })));
+        }
+
+        // Redirects, client-side navigations and subresources are vetted like
+        // the first URL; a refused main-frame navigation fails the render
+        const guard = createGuard({ allowlist: process.env.SSRF_ALLOWLIST });
+        let blocked = null;
+        const isMainNavigation = (request) =>
+            request.isNavigationRequest() && request.frame() === page.mainFrame();
+        await page.setRequestInterception(true);
+        page.on(
+            'request',
+            interceptRequests({
+                guard,
+                targetOrigin,
+                authorization,
+                headers,
+                onBlocked: (reason, request) => {
+                    if (!blocked && isMainNavigation(request)) blocked = reason;
+                },
+            })
+        );
+        if (!proxy) {
+            // Chromium resolves again after the check, so also vet where it connected
+            page.on('response', (response) => {
+                const reason = guard.checkAddress(response.remoteAddress().ip);
+                if (reason && !blocked && isMainNavigation(response.request())) blocked = reason;
+            });
+        }
+
         await page.evaluateOnNewDocument(() => {
             Object.defineProperty(navigator, 'webdriver', {
                 get: () => false,
             });
         });
 
-        await page.goto(url, {
-            waitUntil: ['domcontentloaded', 'networkidle0'],
-            timeout: 60000,
-        });
+        try {
+            await page.goto(url, {
+                waitUntil: ['domcontentloaded', 'networkidle0'],
+                timeout: timeoutMs || 60000,
+            });
+        } catch (err) {
+            if (!blocked) throw err;
+        }
+        if (blocked) {
+            await browser.close();
+            return res.status(403).send(blocked);
+        }
         const html = await page.content();
         await browser.close();
 
         res.setHeader('Content-Type', 'text/html');
         res.send(html);
     } catch (err) {
-        console.error('Puppeteer render error:', err);
+        console.error(`Puppeteer render error (request_id=${req.get('X-Request-ID') || '-'}):`, err);
         if (browser) await browser.close();
         res.status(500).send('Failed to render page');
     }
@@ -0,0 +1,123 @@
+const dns = require('node:dns').promises;
+const net = require('node:net');
+
+// Ranges Chromium may not load, the same ones the Go SSRF guard refuses:
+// loopback, private, link-local (cloud metadata), multicast and reserved.
+const BLOCKED_RANGES = [
+    ['0.0.0.0', 8, 'ipv4'],
+    ['10.0.0.0', 8, 'ipv4'],
+    ['100.64.0.0', 10, 'ipv4'],
+    ['127.0.0.0', 8, 'ipv4'],
+    ['169.254.0.0', 16, 'ipv4'],
+    ['172.16.0.0', 12, 'ipv4'],
+    ['192.0.0.0', 24, 'ipv4'],
+    ['192.168.0.0', 16, 'ipv4'],
+    ['198.18.0.0', 15, 'ipv4'],
+    ['224.0.0.0', 4, 'ipv4'],
+    ['240.0.0.0', 4, 'ipv4'],
+    ['::', 128, 'ipv6'],
+    ['::1', 128, 'ipv6'],
+    ['64:ff9b::', 96, 'ipv6'],
+    ['fc00::', 7, 'ipv6'],
+    ['fe80::', 10, 'ipv6'],
+    ['ff00::', 8, 'ipv6'],
+];
+
+const blockedRanges = new net.BlockList();
+for (const [address, prefix, type] of BLOCKED_RANGES) {
+    blockedRanges.addSubnet(address, prefix, type);
+}
+
+// normalizeIP strips IPv6 brackets and unmaps IPv4-mapped addresses.
+function normalizeIP(ip) {
+    let addr = String(ip || '').replace(/^\[|\]$/g, '').toLowerCase();
+    const mapped = addr.match(/^::ffff:(\d+\.\d+\.\d+\.\d+)$/);
+    if (mapped) addr = mapped[1];
+    return addr;
+}
+
+// createGuard returns the checks for one render. allowlist is the
+// comma-separated SSRF_ALLOWLIST of hostnames, IPs and CIDRs that may be
+// reached although they are not publicly routable.
+function createGuard({ allowlist = '', lookup = (host) => dns.lookup(host, { all: true }) } = {}) {
+    const allowedHosts = new Set();
+    const allowedNets = new net.BlockList();
+    for (let entry of String(allowlist).split(',')) {
+        entry = entry.trim().toLowerCase();
+        if (!entry) continue;
+        const [address, prefix] = entry.split('/');
+        const type = net.isIP(address) === 6 ? 'ipv6' : 'ipv4';
+        if (prefix !== undefined && net.isIP(address)) {
+            allowedNets.addSubnet(address, Number(prefix), type);
+        } else if (net.isIP(entry)) {
+            allowedNets.addAddress(entry, type);
+        } else {
+            allowedHosts.add(entry);
+        }
+    }
+
+    // checkAddress returns why ip may not be reached, or null.
+    function checkAddress(ip, host) {
+        const addr = normalizeIP(ip);
+        const type = net.isIP(addr) === 6 ? 'ipv6' : net.isIP(addr) === 4 ? 'ipv4' : null;
+        if (!type || allowedNets.check(addr, type) || !blockedRanges.check(addr, type)) return null;
+        return `blocked outbound connection to ${host || addr} (${addr}): address is not publicly routable`;
+    }
+
+    // checkURL resolves rawURL's host and returns why it may not be loaded,
+    // or null. Schemes that never touch the network are let through.
+    async function checkURL(rawURL) {
+        const u = new URL(rawURL);
+        if (['data:', 'blob:', 'about:'].includes(u.protocol)) return null;
+        if (u.protocol !== 'http:' && u.protocol !== 'https:') {
+            return `unsupported URL scheme ${u.protocol}`;
+        }
+        const host = u.hostname.replace(/^\[|\]$/g, '').toLowerCase().replace(/\.$/, '');
+        if (allowedHosts.has(host)) return null;
+        if (net.isIP(host)) return checkAddress(host, host);
+        const addresses = await lookup(host);
+        for (const { address } of addresses) {
+            const reason = checkAddress(address, host);
+            if (reason) return reason;
+        }
+        return null;
+    }
+
+    return { checkAddress, checkURL };
+}
+
+// interceptRequests returns the page's request handler. Every request,
+// redirects and subresources included, is vetted by guard; a refused one is
+// aborted and reported through onBlocked. Requests to targetOrigin carry the
+// caller's headers and authorization.
+function interceptRequests({ guard, targetOrigin, authorization, headers, onBlocked = () => {} }) {
+    return async (request) => {
+        let reason;
+        try {
+            reason = await guard.checkURL(request.url());
+        } catch (err) {
+            // Unresolvable hosts fail in Chromium as well
+            return request.abort('namenotresolved');
+        }
+        if (reason) {
+            onBlocked(reason, request);
+            return request.abort('blockedbyclient');
+        }
+
+        let sameOrigin = false;
+        try {
+            sameOrigin = new URL(request.url()).origin === targetOrigin;
+        } catch (e) {
+            sameOrigin = false;
+        }
+        if (!sameOrigin || (!authorization && !headers)) return request.continue();
+        const own = { ...request.headers() };
+        for (const [name, value] of Object.entries(headers || {})) {
+            own[name.toLowerCase()] = value;
+        }
+        if (authorization) own.authorization = authorization;
+        return request.continue({ headers: own });
+    };
+}
+
+module.exports = { createGuard, interceptRequests, normalizeIP };
@@ -0,0 +1,109 @@
+const test = require('node:test');
+const assert = require('node:assert');
+const { createGuard, interceptRequests } = require('./ssrf');
+
+// Public hostnames resolve without touching DNS
+const lookup = async (host) => {
+    const hosts = {
+        'public.example': [{ address: '93.184.216.34', family: 4 }],
+        'rebound.example': [{ address: '93.184.216.34', family: 4 }, { address: '10.0.0.5', family: 4 }],
+    };
+    if (!hosts[host]) throw new Error(`getaddrinfo ENOTFOUND ${host}`);
+    return hosts[host];
+};
+
+// fakeRequest records how the interceptor settled it.
+function fakeRequest(url, { navigation = true, redirectChain = [] } = {}) {
+    return {
+        outcome: null,
+        url: () => url,
+        isNavigationRequest: () => navigation,
+        redirectChain: () => redirectChain,
+        headers: () => ({ accept: '*/*' }),
+        continue(overrides) {
+            this.outcome = { continued: true, overrides };
+        },
+        abort(reason) {
+            this.outcome = { aborted: reason };
+        },
+    };
+}
+
+test('a public page that redirects to loopback is blocked', async () => {
+    const blocked = [];
+    const handler = interceptRequests({
+        guard: createGuard({ lookup }),
+        targetOrigin: 'http://public.example',
+        onBlocked: (reason, request) => blocked.push({ reason, request }),
+    });
+
+    const first = fakeRequest('http://public.example/start');
+    await handler(first);
+    assert.deepStrictEqual(first.outcome, { continued: true, overrides: undefined });
+
+    // Chromium follows the 302 with a new request carrying the redirect chain
+    const redirected = fakeRequest('http://127.0.0.1:8080/admin', { redirectChain: [first] });
+    await handler(redirected);
+    assert.deepStrictEqual(redirected.outcome, { aborted: 'blockedbyclient' });
+    assert.strictEqual(blocked.length, 1);
+    assert.match(blocked[0].reason, /127\.0\.0\.1.*not publicly routable/);
+    assert.strictEqual(blocked[0].request, redirected);
+});
+
+test('metadata, private and mapped addresses are refused', async () => {
+    const guard = createGuard({ lookup });
+    for (const url of [
+        'http://169.254.169.254/latest/meta-data/',
+        'http://192.168.1.1/',
+        'http://[::1]/',
+        'http://[::ffff:127.0.0.1]/',
+        'http://rebound.example/',
+        'file:///etc/passwd',
+    ]) {
+        assert.ok(await guard.checkURL(url), `expected ${url} to be refused`);
+    }
+    assert.strictEqual(await guard.checkURL('https://public.example/app.js'), null);
+    assert.strictEqual(await guard.checkURL('data:text/plain,hi'), null);
+    assert.ok(guard.checkAddress('::ffff:10.1.2.3'));
+    assert.strictEqual(guard.checkAddress('93.184.216.34'), null);
+});
+
+test('SSRF_ALLOWLIST entries may be reached', async () => {
+    const guard = createGuard({ allowlist: 'intranet.example, 10.20.0.0/16,127.0.0.1', lookup });
+    assert.strictEqual(await guard.checkURL('http://intranet.example/'), null);
+    assert.strictEqual(await guard.checkURL('http://10.20.3.4/'), null);
+    assert.strictEqual(await guard.checkURL('http://127.0.0.1:3000/'), null);
+    assert.ok(await guard.checkURL('http://10.21.0.1/'));
+});
+
+test('authorization and custom headers are only added for the target origin', async () => {
+    const handler = interceptRequests({
+        guard: createGuard({ lookup }),
+        targetOrigin: 'http://public.example',
+        authorization: 'Bearer secret',
+        headers: { 'X-Tenant': 'acme' },
+    });
+    const own = fakeRequest('http://public.example/page');
+    const third = fakeRequest('https://public.example/other-scheme', { navigation: false });
+    await handler(own);
+    await handler(third);
+    assert.strictEqual(own.outcome.overrides.headers.authorization, 'Bearer secret');
+    assert.strictEqual(own.outcome.overrides.headers['x-tenant'], 'acme');
+    assert.deepStrictEqual(third.outcome, { continued: true, overrides: undefined });
+
+    const headersOnly = interceptRequests({
+        guard: createGuard({ lookup }),
+        targetOrigin: 'http://public.example',
+        headers: { 'X-Tenant': 'acme' },
+    });
+    const page = fakeRequest('http://public.example/');
+    await headersOnly(page);
+    assert.deepStrictEqual(page.outcome.overrides.headers, { accept: '*/*', 'x-tenant': 'acme' });
+});
+
+test('unresolvable hosts are aborted', async () => {
+    const handler = interceptRequests({ guard: createGuard({ lookup }), targetOrigin: 'http://public.example' });
+    const request = fakeRequest('http://missing.example/');
+    await handler(request);
+    assert.deepStrictEqual(request.outcome, { aborted: 'namenotresolved' });
+});
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/gate"
+	"web-analyzer/internal/helpers"
+)
+
+// Gate exit codes.
+const (
+	exitPassed    = 0
+	exitViolation = 1
+	exitError     = 2
+)
+
+// stringList collects a repeatable, comma-separated flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// runGate analyzes one URL, evaluates the thresholds and returns the process
+// exit code: 0 when every check passes, 1 on a violation, 2 on usage or
+// analysis errors.
+func runGate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: webanalyzer gate [flags] <url>")
+		fs.PrintDefaults()
+	}
+
+	var t gate.Thresholds
+	var forbidden stringList
+	fs.IntVar(&t.MaxInaccessibleLinks, "max-inaccessible", 0, "maximum number of inaccessible links (-1 disables the check)")
+	fs.BoolVar(&t.RequireTitle, "require-title", false, "fail when the page has no <title>")
+	fs.BoolVar(&t.RequireSingleH1, "require-single-h1", false, "fail unless the page has exactly one <h1>")
+	fs.Var(&forbidden, "forbid-html-version", "fail when the HTML version contains this text (repeatable or comma-separated)")
+	fs.BoolVar(&t.FailOnPartial, "fail-on-partial", false, "fail when the analysis was cut short")
+	summaryPath := fs.String("summary", "", "write a JSON summary to this file")
+	renderMode := fs.String("render", string(analyzer.RenderAuto), "render mode: auto, always or never")
+	timeout := fs.Duration("timeout", constants.AnalysisTimeout, "wall-time budget for the analysis; slower pages yield a partial result")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	t.ForbiddenHTMLVersions = forbidden
+
+	opts := analyzer.AnalyzeOptions{RenderMode: analyzer.RenderMode(*renderMode), MaxDuration: *timeout}
+	if t.MaxInaccessibleLinks < 0 {
+		opts.SkipLinkCheck = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	result, err := analyzer.Analyze(ctx, fs.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Analysis of %s failed after %s: %v\n", fs.Arg(0), time.Since(start).Round(time.Millisecond), err)
+		// CI still gets an artifact saying why the gate could not run
+		if *summaryPath != "" {
+			pageURL, _ := helpers.StripURLCredentials(fs.Arg(0))
+			if err := gate.WriteJSON(*summaryPath, gate.Failed(pageURL, err, time.Since(start))); err != nil {
+				fmt.Fprintln(stderr, err)
+			}
+		}
+		return exitError
+	}
+
+	summary := gate.Evaluate(result, t)
+	gate.WriteText(stdout, summary)
+	if *summaryPath != "" {
+		if err := gate.WriteJSON(*summaryPath, summary); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	}
+	if !summary.Passed {
+		return exitViolation
+	}
+	return exitPassed
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"web-analyzer/internal/gate"
+	"web-analyzer/internal/testutil"
+)
+
+func TestRunGate_ExitCodes(t *testing.T) {
+	testutil.AllowLoopback(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Write([]byte(`<!DOCTYPE html><html><title>Home</title><h1>Hi</h1><a href="/missing">x</a></html>`))
+		}
+	}))
+	defer ts.Close()
+
+	summary := filepath.Join(t.TempDir(), "gate.json")
+	var stdout, stderr bytes.Buffer
+
+	code := runGate([]string{"-require-title", "-require-single-h1", "-render", "never", "-summary", summary, ts.URL}, &stdout, &stderr)
+	if code != exitViolation {
+		t.Errorf("Expected exit %d for broken link, got %d (%s)", exitViolation, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "[FAIL] max-inaccessible-links") {
+		t.Errorf("Expected failing link check in output:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(summary); err != nil {
+		t.Errorf("Expected JSON summary to be written: %v", err)
+	}
+
+	stdout.Reset()
+	code = runGate([]string{"-max-inaccessible", "1", "-forbid-html-version", "XHTML,HTML 4", "-render", "never", ts.URL}, &stdout, &stderr)
+	if code != exitPassed {
+		t.Errorf("Expected exit %d, got %d:\n%s", exitPassed, code, stdout.String())
+	}
+
+	if code := runGate(nil, &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit %d without a URL, got %d", exitError, code)
+	}
+}
+
+func TestRunGate_AnalysisErrorWritesSummary(t *testing.T) {
+	summary := filepath.Join(t.TempDir(), "gate.json")
+	var stdout, stderr bytes.Buffer
+
+	code := runGate([]string{"-render", "never", "-summary", summary, "http://127.0.0.1:1/"}, &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("Expected exit %d for an unreachable page, got %d", exitError, code)
+	}
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatalf("Expected a summary for the failed analysis: %v", err)
+	}
+	var s gate.Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("Invalid summary %s: %v", data, err)
+	}
+	if s.Passed || s.URL != "http://127.0.0.1:1/" || !strings.Contains(s.Error, "connect") {
+		t.Errorf("Expected a failed summary with the error, got %+v", s)
+	}
+}
@@ -70,33 +70,112 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
+	"strconv"
+	"time"
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/logging"
 	"web-analyzer/internal/server"
 	"web-analyzer/pkg/embed"
 )
 
 func main() {
+	if err := logging.Setup(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"), os.Stderr); err != nil {
+		fatal("Invalid logging configuration", err)
+	}
+	if err := helpers.SetDefaultProxy(os.Getenv("OUTBOUND_PROXY")); err != nil {
+		fatal("Invalid OUTBOUND_PROXY", err)
+	}
+	if v := os.Getenv("FETCH_MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && n <= 0 {
+			err = fmt.Errorf("must be positive, got %d", n)
+		}
+		if err != nil {
+			fatal("Invalid FETCH_MAX_BODY_BYTES", err)
+		}
+		helpers.DefaultFetchLimits.MaxBodyBytes = n
+	}
+	if v := os.Getenv("FETCH_READ_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil && d <= 0 {
+			err = fmt.Errorf("must be positive, got %v", d)
+		}
+		if err != nil {
+			fatal("Invalid FETCH_READ_TIMEOUT", err)
+		}
+		helpers.DefaultFetchLimits.ReadTimeout = d
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "gate" {
+		os.Exit(runGate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	formTmpl, err := embed.LoadEmbeddedTemplateFile("form.html")
 	if err != nil {
-		log.Fatalf("Failed to load form.html: %v", err)
+		fatal("Failed to load form.html", err)
 	}
 	resultTmpl, err := embed.LoadEmbeddedTemplateFile("result.html")
 	if err != nil {
-		log.Fatalf("Failed to load result.html: %v", err)
+		fatal("Failed to load result.html", err)
 	}
 	server.SetTemplates(formTmpl, resultTmpl)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", server.ShowForm)
-	mux.Handle("/api/analyze", server.Chain(
-		http.HandlerFunc(server.ErrorHandler(server.HandleAnalyzeJSON)),
-		server.RateLimit,
-	))
-	mux.HandleFunc("/result", server.ShowResultPage)
+	cacheConfig := analyzer.CacheConfig{StripTrackingParams: os.Getenv("CACHE_STRIP_TRACKING") == "true"}
+	if v := os.Getenv("CACHE_MAX_ENTRIES"); v != "" {
+		if cacheConfig.MaxEntries, err = strconv.Atoi(v); err != nil {
+			fatal("Invalid CACHE_MAX_ENTRIES", err)
+		}
+	}
+	if v := os.Getenv("CACHE_TTL"); v != "" {
+		if cacheConfig.TTL, err = time.ParseDuration(v); err != nil {
+			fatal("Invalid CACHE_TTL", err)
+		}
+	}
+	analyzer.ConfigureCache(cacheConfig)
+
+	var linkCacheConfig analyzer.LinkCacheConfig
+	if v := os.Getenv("LINK_CACHE_SUCCESS_TTL"); v != "" {
+		if linkCacheConfig.SuccessTTL, err = time.ParseDuration(v); err != nil {
+			fatal("Invalid LINK_CACHE_SUCCESS_TTL", err)
+		}
+	}
+	if v := os.Getenv("LINK_CACHE_FAILURE_TTL"); v != "" {
+		if linkCacheConfig.FailureTTL, err = time.ParseDuration(v); err != nil {
+			fatal("Invalid LINK_CACHE_FAILURE_TTL", err)
+		}
+	}
+	analyzer.ConfigureLinkCache(linkCacheConfig)
+
+	trustedProxies, err := server.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		fatal("Invalid TRUSTED_PROXIES", err)
+	}
+	analyzeLimiter := newRateLimiter("/api/analyze", "RATE_LIMIT_ANALYZE", constants.AnalyzeRateLimit, trustedProxies)
+	batchLimiter := newRateLimiter("/api/batch", "RATE_LIMIT_BATCH", constants.BatchRateLimit, trustedProxies)
+
+	// API keys are required once a keys file or an admin token is configured
+	var keys *server.APIKeyStore
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		if keys, err = server.LoadAPIKeys(path); err != nil {
+			fatal("Failed to load API keys", err)
+		}
+	} else if adminToken != "" {
+		keys = server.NewAPIKeyStore()
+	}
 
-	loggedMux := server.LoggingMiddleware(mux)
+	handler := server.NewMux(server.RoutesConfig{
+		AnalyzeLimiter: analyzeLimiter,
+		BatchLimiter:   batchLimiter,
+		Keys:           keys,
+		AdminToken:     adminToken,
+	})
 	host := os.Getenv("HOST")
 	if host == "" {
 		host = "0.0.0.0"
@@ -106,8 +185,27 @@ func main() {
 		port = "8080"
 	}
 	addr := fmt.Sprintf("%s:%s", host, port)
-	log.Printf("Server starting on http://%s\n", addr)
-	if err := http.ListenAndServe(addr, loggedMux); err != nil {
-		log.Fatal(err)
+	slog.Info("Server starting", "addr", "http://"+addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		fatal("Server stopped", err)
+	}
+}
+
+// newRateLimiter builds the limiter for route from envVar, or fallback when unset.
+func newRateLimiter(route, envVar, fallback string, trusted []netip.Prefix) *server.RateLimiter {
+	spec := os.Getenv(envVar)
+	if spec == "" {
+		spec = fallback
+	}
+	rate, burst, err := server.ParseRateLimit(spec)
+	if err != nil {
+		fatal("Invalid "+envVar, err)
 	}
+	return server.NewRateLimiter(server.RateLimitConfig{Route: route, Rate: rate, Burst: burst, TrustedProxies: trusted})
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
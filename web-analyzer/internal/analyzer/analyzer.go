@@ -2,8 +2,11 @@ package analyzer
 
 import (
 	"bytes"
+	"context"
+	stderrors "errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -11,7 +14,9 @@ import (
 	"sync"
 	"time"
 
+	"web-analyzer/internal/constants"
 	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/metrics"
 	"web-analyzer/pkg/errors"
 
 	"golang.org/x/net/html"
@@ -30,6 +35,9 @@ type Heading struct {
 
 type Result struct {
 	PageURL           string
+	FinalURL          string
+	Redirects         []helpers.RedirectHop
+	ClientRedirects   []ClientRedirect
 	HTMLVersion       string
 	Title             string
 	Headings          []Heading
@@ -38,13 +46,37 @@ type Result struct {
 	AccessibleLinks   []NamedLink
 	InaccessibleLinks []NamedLink
 	HasLoginForm      bool
+	MixedContent      []MixedContent
+	BotProtection     *helpers.BotDetection
+	Rendered          bool
+	BodyTruncated     bool
+	Validators        *helpers.Validators
+	Revalidated       bool
+	LinkCache         LinkCacheStats
+	Partial           bool      // analysis was cut short by cancellation, a deadline or a budget
+	Skipped           []Skipped // parts cut short by a budget
 	AnalysisDuration  time.Duration
 }
 
 type LinkCheckerConfig struct {
 	MaxConcurrency int
 	Timeout        time.Duration
-	Logger         func(format string, args ...interface{}) // nil = silent
+	UserAgent      string                                   // "" = constants.DefaultUserAgent
+	Logger         func(format string, args ...interface{}) // optional; failures are also logged via slog at debug level
+
+	// Headers and Credentials are attached only to links on PageURL's origin
+	Headers     map[string]string
+	Credentials *helpers.Credentials
+	PageURL     *url.URL
+
+	Proxy  string          // "" = Egress's default proxy
+	Egress *helpers.Egress // nil = the process-wide egress
+	// Client sends the checks instead of a client on Egress; Proxy is then
+	// ignored and Timeout bounds each check through its context.
+	Client *http.Client
+
+	SkipCache bool              // check every link, only storing the outcomes in the link-status cache
+	Metrics   *metrics.Analyzer // nil = metrics.DefaultAnalyzer
 }
 
 func stripPort(hostport string) string {
@@ -56,37 +88,213 @@ func stripPort(hostport string) string {
 }
 
 func AnalyzePage(pageURL string) (*Result, error) {
+	return AnalyzePageContext(context.Background(), pageURL)
+}
+
+// AnalyzePageContext fetches, renders if needed, and parses pageURL. Cancelling
+// ctx aborts any outstanding fetch or render. Links are not checked.
+func AnalyzePageContext(ctx context.Context, pageURL string) (*Result, error) {
+	return defaultPipeline().analyzePage(ctx, pageURL, AnalyzeOptions{}.withDefaults())
+}
+
+// Analyze runs the full pipeline for pageURL: fetch, render according to
+// opts.RenderMode, parse, and check links unless opts.SkipLinkCheck is set.
+// If ctx ends during link checking the partial result is returned with
+// Partial set. Exhausted budgets degrade the result the same way and are
+// listed in Result.Skipped.
+func Analyze(ctx context.Context, pageURL string, opts AnalyzeOptions) (*Result, error) {
+	return defaultPipeline().Analyze(ctx, pageURL, opts)
+}
+
+// Analyze is the package-level Analyze run with p's dependencies.
+func (p Pipeline) Analyze(ctx context.Context, pageURL string, opts AnalyzeOptions) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+
+	ctx, cancel := context.WithTimeoutCause(ctx, opts.MaxDuration, errDurationBudget)
+	defer cancel()
+
+	// user:password in the URL is used as basic auth and never echoed back
+	pageURL, urlCreds := helpers.StripURLCredentials(pageURL)
+	if urlCreds != nil && opts.Credentials == nil {
+		opts.Credentials = urlCreds
+	}
+
+	start := time.Now()
+	result, err := p.analyzePage(ctx, pageURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.SkipLinkCheck {
+		p.checkLinks(ctx, result, opts)
+	}
+	result.AnalysisDuration = time.Since(start)
+	p.meters().PhaseDuration.Observe(result.AnalysisDuration.Seconds(), metrics.PhaseTotal)
+	return result, nil
+}
+
+// checkLinks classifies result's links, at most opts.MaxLinks of them, within
+// ctx's remaining wall-time budget.
+func (p Pipeline) checkLinks(ctx context.Context, result *Result, opts AnalyzeOptions) {
+	linkStart := time.Now()
+	skipped := len(result.Skipped)
+	links := append(append([]NamedLink(nil), result.InternalLinks...), result.ExternalLinks...)
+	if len(links) > opts.MaxLinks {
+		result.skip(PartLinkCheck, BudgetMaxLinks, "checked %d of %d links", opts.MaxLinks, len(links))
+		links = links[:opts.MaxLinks]
+	}
+
+	pageBase, _ := url.Parse(result.PageURL)
+	config := opts.linkCheckerConfig(pageBase)
+	config.Egress, config.Client, config.Metrics = p.Egress, p.LinkClient, p.meters()
+	var err error
+	result.AccessibleLinks, result.InaccessibleLinks, result.LinkCache, err = classifyLinks(ctx, links, config, p.LinkCache)
+	if err != nil {
+		result.Partial = true
+		if budgetExhausted(ctx, errDurationBudget) {
+			checked := len(result.AccessibleLinks) + len(result.InaccessibleLinks)
+			result.skip(PartLinkCheck, BudgetMaxDuration, "%d of %d links left unchecked after %v", len(links)-checked, len(links), opts.MaxDuration)
+		}
+	}
+	recordBudgets(p.meters(), result.Skipped[skipped:])
+	p.meters().PhaseDuration.ObserveSince(linkStart, metrics.PhaseLinkCheck)
+	slog.InfoContext(ctx, "links checked",
+		"url", result.PageURL,
+		"accessible", len(result.AccessibleLinks),
+		"inaccessible", len(result.InaccessibleLinks),
+		"partial", result.Partial,
+		"duration", time.Since(linkStart),
+	)
+}
+
+func (p Pipeline) analyzePage(ctx context.Context, pageURL string, opts AnalyzeOptions) (*Result, error) {
+	m := p.meters()
 	start := time.Now()
 	parsedURL, err := url.ParseRequestURI(pageURL)
 	if err != nil {
-		return nil, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("invalid URL: %v", err)}
+		return nil, errors.New(errors.CodeInvalidURL, err, "invalid URL: %v", err)
 	}
 
-	data, isBotBlocked, err := helpers.TryStandardFetch(pageURL)
+	fetchOpts := opts.fetchOptions()
+	fetchOpts.Egress, fetchOpts.Limits = p.Egress, p.Limits
+	fetched, err := p.Fetch(ctx, pageURL, fetchOpts)
+	m.PhaseDuration.ObserveSince(start, metrics.PhaseFetch)
 	if err != nil {
 		return nil, err
 	}
-
-	// Retry with Puppeteer render if bot-block detected
-	if isBotBlocked {
-		rendered, err := helpers.FetchRenderedDOM(pageURL)
-		if err != nil {
-			return nil, &errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("puppeteer render failed: %v", err)}
+	// Checked here as well as in the built-in fetcher, since Fetch may be an
+	// embedder's
+	if _, err := helpers.AnalyzableContent(fetched.ContentType, fetched.Body); err != nil {
+		return nil, err
+	}
+	data := fetched.Body
+	truncated := fetched.Truncated
+	var skipped []Skipped
+
+	// Retry with Puppeteer render if bot-block detected, unless the caller decided otherwise
+	rendered := opts.RenderMode == RenderAlways || (opts.RenderMode == RenderAuto && fetched.BotProtection != nil)
+	if rendered {
+		reason, vendor := metrics.RenderReasonRequested, ""
+		if fetched.BotProtection != nil {
+			reason, vendor = metrics.RenderReasonBotProtection, fetched.BotProtection.Vendor
 		}
-		data = rendered
+		m.RenderFallbacks.Inc(reason, vendor)
+		slog.InfoContext(ctx, "rendering page", "url", pageURL, "reason", reason, "vendor", vendor)
+
+		renderStart := time.Now()
+		renderCtx, cancelRender := context.WithTimeoutCause(ctx, opts.RenderTimeout, errRenderBudget)
+		renderOpts := opts.renderOptions()
+		renderOpts.Egress, renderOpts.Limits = p.Egress, p.Limits
+		dom, err := p.Render(renderCtx, pageURL, renderOpts)
+		renderBudget := ""
+		switch {
+		case budgetExhausted(ctx, errDurationBudget):
+			renderBudget = BudgetMaxDuration
+		case budgetExhausted(renderCtx, errRenderBudget):
+			renderBudget = BudgetMaxRenderTime
+		}
+		cancelRender()
+		m.PhaseDuration.ObserveSince(renderStart, metrics.PhaseRender)
+
+		switch {
+		case err == nil:
+			data = dom
+			truncated = false
+			if max := p.Limits.WithDefaults().MaxBodyBytes; int64(len(data)) > max {
+				data = data[:max]
+				truncated = true
+			}
+		case renderBudget != "":
+			// Out of render time: analyze the fetched HTML rather than nothing
+			slog.WarnContext(ctx, "render budget exhausted", "url", pageURL, "budget", renderBudget, "duration", time.Since(renderStart))
+			rendered = false
+			skipped = append(skipped, Skipped{
+				Part:   PartRender,
+				Budget: renderBudget,
+				Detail: fmt.Sprintf("render gave up after %v; the fetched HTML was analyzed instead", time.Since(renderStart).Round(time.Millisecond)),
+			})
+		default:
+			slog.WarnContext(ctx, "render failed", "url", pageURL, "error", err, "duration", time.Since(renderStart))
+			if ctx.Err() != nil {
+				return nil, helpers.ContextError(ctx, "render")
+			}
+			var httpErr *errors.HTTPError
+			if stderrors.As(err, &httpErr) {
+				return nil, err
+			}
+			return nil, errors.New(errors.CodeRenderUnavailable, err, "puppeteer render failed: %v", err)
+		}
+	}
+
+	// A spent wall-time budget still lets the page already in hand be parsed
+	if ctx.Err() != nil && !budgetExhausted(ctx, errDurationBudget) {
+		return nil, helpers.ContextError(ctx, "parse")
 	}
 
+	parseStart := time.Now()
 	htmlVersion := detectHTMLVersion(data)
 	doc, err := html.Parse(strings.NewReader(string(data)))
 	if err != nil {
-		return nil, &errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("failed to parse HTML: %v", err)}
+		return nil, errors.New(errors.CodeParse, err, "failed to parse HTML: %v", err)
+	}
+
+	// Links are resolved against where the redirects ended up
+	baseURL := parsedURL
+	if finalURL, err := url.Parse(fetched.FinalURL); err == nil && fetched.FinalURL != "" {
+		baseURL = finalURL
 	}
 
 	result := &Result{
-		PageURL:     pageURL,
-		HTMLVersion: htmlVersion,
+		PageURL:       pageURL,
+		FinalURL:      baseURL.String(),
+		Redirects:     fetched.Redirects,
+		HTMLVersion:   htmlVersion,
+		BotProtection: fetched.BotProtection,
+		Rendered:      rendered,
+		BodyTruncated: truncated,
+		Validators:    fetched.Validators,
 	}
-	extractInfo(doc, parsedURL, result)
+	for _, s := range skipped {
+		result.skip(s.Part, s.Budget, "%s", s.Detail)
+	}
+	if p.TagConfig != nil {
+		extractInfoWith(doc, baseURL, result, opts.MaxDOMNodes, p.TagConfig)
+	} else {
+		extractInfo(ctx, doc, baseURL, result, opts.MaxDOMNodes)
+	}
+	recordBudgets(m, result.Skipped)
+	m.PhaseDuration.ObserveSince(parseStart, metrics.PhaseParse)
+	slog.DebugContext(ctx, "page parsed",
+		"url", pageURL,
+		"html_version", htmlVersion,
+		"headings", len(result.Headings),
+		"internal_links", len(result.InternalLinks),
+		"external_links", len(result.ExternalLinks),
+		"duration", time.Since(parseStart),
+	)
 	result.AnalysisDuration = time.Since(start)
 	return result, nil
 }
@@ -145,21 +353,39 @@ func getTextContent(n *html.Node) string {
 }
 
 // Walk the DOM and extract info.
-func extractInfo(n *html.Node, baseURL *url.URL, result *Result) {
+// Walking stops after maxNodes elements (0 = no limit) and the result is
+// marked partial.
+func extractInfo(ctx context.Context, n *html.Node, baseURL *url.URL, result *Result, maxNodes int) {
+	cfg, err := LoadTagConfig()
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load tag config", "error", err)
+		panic(errors.New(errors.CodeInternal, err, "Failed to load config: %v", err))
+	}
+	slog.DebugContext(ctx, "loaded headings config", "headings", cfg.Headings)
+	extractInfoWith(n, baseURL, result, maxNodes, cfg)
+}
+
+// extractInfoWith is extractInfo with the heading tags taken from cfg.
+func extractInfoWith(n *html.Node, baseURL *url.URL, result *Result, maxNodes int, cfg *TagConfig) {
 	var rawInternal []string
 	var rawExternal []string
 	var allLinks []string
 
-	cfg, err := LoadTagConfig()
-	if err != nil {
-		log.Printf("Failed to load config: %v", err)
-		panic(&errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("Failed to load config: %v", err)})
-	}
-	log.Println("Loaded headings config:", cfg.Headings)
+	// Mixed content only applies to pages served over HTTPS
+	checkMixed := strings.EqualFold(baseURL.Scheme, "https")
 
+	nodes, domTruncated := 0, false
 	var walk func(*html.Node)
 	walk = func(n *html.Node) {
 		if n.Type == html.ElementNode {
+			if maxNodes > 0 && nodes >= maxNodes {
+				domTruncated = true
+				return
+			}
+			nodes++
+			if checkMixed {
+				result.MixedContent = append(result.MixedContent, findMixedContent(n, baseURL)...)
+			}
 			switch n.Data {
 			case "title":
 				if n.FirstChild != nil {
@@ -185,6 +411,14 @@ func extractInfo(n *html.Node, baseURL *url.URL, result *Result) {
 						}
 					}
 				}
+			case "meta":
+				if redirect := findMetaRefresh(n, baseURL); redirect != nil {
+					result.ClientRedirects = append(result.ClientRedirects, *redirect)
+				}
+			case "script":
+				if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+					result.ClientRedirects = append(result.ClientRedirects, findJSRedirects(n.FirstChild.Data, baseURL)...)
+				}
 			case "input":
 				for _, attr := range n.Attr {
 					if attr.Key == "type" && strings.ToLower(attr.Val) == "password" {
@@ -207,59 +441,178 @@ func extractInfo(n *html.Node, baseURL *url.URL, result *Result) {
 		}
 	}
 	walk(n)
+	if domTruncated {
+		result.skip(PartDOM, BudgetMaxDOMNodes, "stopped after %d elements; later headings and links are missing", nodes)
+	}
 
 	result.InternalLinks = ToNamedLinks(rawInternal)
 	result.ExternalLinks = ToNamedLinks(rawExternal)
 }
 
 func isLinkAccessible(link string, timeout time.Duration, logger func(string, ...interface{})) bool {
-	client := &http.Client{Timeout: timeout}
-	req, err := http.NewRequest("HEAD", link, nil)
+	return isLinkAccessibleContext(context.Background(), link, LinkCheckerConfig{Timeout: timeout, Logger: logger})
+}
+
+func isLinkAccessibleContext(ctx context.Context, link string, config LinkCheckerConfig) bool {
+	category, ok := checkLink(ctx, link, config)
+	config.meters().LinkChecks.Inc(category)
+	return ok
+}
+
+// checkLink sends the HEAD request and reports the outcome category recorded
+// in the link-check metrics alongside whether the link counts as accessible.
+func checkLink(ctx context.Context, link string, config LinkCheckerConfig) (string, bool) {
+	client, reqCtx := config.Client, ctx
+	if client != nil {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	} else {
+		proxy := config.Egress.DefaultProxy()
+		if config.Proxy != "" {
+			parsed, err := helpers.ParseProxyURL(config.Proxy)
+			if err != nil {
+				config.logFailure(ctx, link, "proxy invalid", "error", err)
+				return "error", false
+			}
+			proxy = parsed
+		}
+		client = config.Egress.Client(config.Timeout, proxy)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, "HEAD", link, nil)
 	if err != nil {
-		if logger != nil {
-			logger("HEAD request creation failed for %s: %v", link, err)
+		config.logFailure(ctx, link, "creation failed", "error", err)
+		return "error", false
+	}
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = constants.DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if helpers.SameOrigin(config.PageURL, req.URL) {
+		for name, value := range config.Headers {
+			req.Header.Set(name, value)
 		}
-		return false
 	}
+	config.Credentials.Apply(req, config.PageURL)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		if logger != nil {
-			logger("HEAD request failed for %s: %v", link, err)
-		}
-		return false
+		category := linkErrorCategory(ctx, err)
+		config.logFailure(ctx, link, "failed", category, err)
+		return category, false
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode >= 200 && resp.StatusCode < 400
+	category := fmt.Sprintf("%dxx", resp.StatusCode/100)
+	accessible := resp.StatusCode >= 200 && resp.StatusCode < 400
+	if !accessible {
+		slog.DebugContext(ctx, "link inaccessible", "link", link, "status", resp.StatusCode)
+	}
+	return category, accessible
+}
+
+func (c LinkCheckerConfig) meters() *metrics.Analyzer {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+	return metrics.DefaultAnalyzer
+}
+
+// logFailure reports a link check that could not complete, through slog and
+// the legacy Logger callback when one is set.
+func (c LinkCheckerConfig) logFailure(ctx context.Context, link, what, category string, err error) {
+	slog.DebugContext(ctx, "link check "+what, "link", link, "category", category, "error", err)
+	if c.Logger != nil {
+		c.Logger("HEAD request %s for %s: %v", what, link, err)
+	}
+}
+
+// linkErrorCategory classifies a failed link check.
+func linkErrorCategory(ctx context.Context, err error) string {
+	var blocked *helpers.BlockedAddressError
+	var netErr net.Error
+	switch {
+	case ctx.Err() != nil:
+		return "cancelled"
+	case stderrors.As(err, &blocked):
+		return "blocked"
+	case stderrors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+	return "error"
 }
 
 func ClassifyLinksConcurrently(links []NamedLink, config LinkCheckerConfig) (accessible, inaccessible []NamedLink) {
+	accessible, inaccessible, _ = ClassifyLinksConcurrentlyContext(context.Background(), links, config)
+	return
+}
+
+// ClassifyLinksConcurrentlyContext checks links until ctx ends. Links that were
+// not checked in time are left out of both lists and ctx.Err() is returned so
+// the caller can flag the result as partial.
+func ClassifyLinksConcurrentlyContext(ctx context.Context, links []NamedLink, config LinkCheckerConfig) (accessible, inaccessible []NamedLink, err error) {
+	accessible, inaccessible, _, err = classifyLinks(ctx, links, config, linkCache)
+	return accessible, inaccessible, err
+}
+
+// classifyLinks is ClassifyLinksConcurrentlyContext that also reports how many
+// outcomes came from cache, which may be nil. Cached links are answered
+// without waiting for a check slot.
+func classifyLinks(ctx context.Context, links []NamedLink, config LinkCheckerConfig, cache *LinkStatusCache) (accessible, inaccessible []NamedLink, stats LinkCacheStats, err error) {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, config.MaxConcurrency)
 	mu := sync.Mutex{}
+	record := func(link NamedLink, ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ok {
+			accessible = append(accessible, link)
+		} else {
+			inaccessible = append(inaccessible, link)
+		}
+	}
 
 	for _, link := range links {
+		key := ""
+		if cache != nil {
+			key = linkCacheKey(link.URL, config)
+		}
+		if key != "" {
+			if ok, hit := cache.get(key); hit && !config.SkipCache {
+				config.meters().LinkCacheHits.Inc()
+				stats.Hits++
+				record(link, ok)
+				continue
+			}
+			config.meters().LinkCacheMisses.Inc()
+			stats.Misses++
+		}
+
 		wg.Add(1)
 		go func(link NamedLink) {
 			defer wg.Done()
-			sem <- struct{}{}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
 			defer func() { <-sem }()
 
-			ok := isLinkAccessible(link.URL, config.Timeout, config.Logger)
-
-			mu.Lock()
-			if ok {
-				accessible = append(accessible, link)
-			} else {
-				inaccessible = append(inaccessible, link)
+			ok := isLinkAccessibleContext(ctx, link.URL, config)
+			if !ok && ctx.Err() != nil {
+				// Cancelled mid-check, so the outcome is unknown
+				return
 			}
-			mu.Unlock()
+			if key != "" {
+				cache.store(key, ok)
+			}
+			record(link, ok)
 		}(link)
 	}
 
 	wg.Wait()
-	return
+	return accessible, inaccessible, stats, ctx.Err()
 }
 
 func ToNamedLinks(links []string) []NamedLink {
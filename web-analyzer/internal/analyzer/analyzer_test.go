@@ -1,12 +1,17 @@
 package analyzer
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/testutil"
 	"web-analyzer/pkg/errors"
 )
 
@@ -17,6 +22,7 @@ func init() {
 }
 
 func TestAnalyzePage_BasicPage(t *testing.T) {
+	testutil.AllowLoopback(t)
 	server := newTestServer(basicTestHTML)
 	defer server.Close()
 
@@ -68,6 +74,7 @@ func TestAnalyzePage_InvalidURL(t *testing.T) {
 // }
 
 func TestAnalyzePage_BadBody(t *testing.T) {
+	testutil.AllowLoopback(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		hj, _ := w.(http.Hijacker)
 		conn, _, _ := hj.Hijack()
@@ -82,6 +89,7 @@ func TestAnalyzePage_BadBody(t *testing.T) {
 }
 
 func TestAnalyzePage_BadHTML(t *testing.T) {
+	testutil.AllowLoopback(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("<html><title>Broken"))
 	}))
@@ -94,6 +102,7 @@ func TestAnalyzePage_BadHTML(t *testing.T) {
 }
 
 func TestAnalyzePage_EmptyBody(t *testing.T) {
+	testutil.AllowLoopback(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(""))
 	}))
@@ -127,6 +136,7 @@ func TestClassifyLinksConcurrently(t *testing.T) {
 }
 
 func TestAnalyzePage_CustomHeadingTags(t *testing.T) {
+	testutil.AllowLoopback(t)
 	html := `<html><body><custom-heading>Custom Title</custom-heading></body></html>`
 	ts := newTestServer(html)
 	defer ts.Close()
@@ -148,6 +158,7 @@ func TestAnalyzePage_CustomHeadingTags(t *testing.T) {
 }
 
 func TestAnalyzePage_LinkClassification(t *testing.T) {
+	testutil.AllowLoopback(t)
 	html := `<a href="/internal">Internal</a><a href="http://external.com">External</a><a href="::bad">Bad</a><a href="">Empty</a>`
 	ts := newTestServer(html)
 	defer ts.Close()
@@ -163,6 +174,7 @@ func TestAnalyzePage_LinkClassification(t *testing.T) {
 }
 
 func TestAnalyzePage_LoginFormDetection(t *testing.T) {
+	testutil.AllowLoopback(t)
 	html := `<form><input type="password" /></form>`
 	ts := newTestServer(html)
 	defer ts.Close()
@@ -241,6 +253,7 @@ func TestHTTPError_Error(t *testing.T) {
 }
 
 func TestAnalyzePage_ReadBodyError(t *testing.T) {
+	testutil.AllowLoopback(t)
 	// Simulate a broken response body
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		hj, ok := w.(http.Hijacker)
@@ -277,6 +290,7 @@ func TestIsLinkAccessible_RequestCreationFails(t *testing.T) {
 }
 
 func TestAnalyzePage_ConfigLoadFailure(t *testing.T) {
+	testutil.AllowLoopback(t)
 	original := LoadTagConfig
 	LoadTagConfig = func() (*TagConfig, error) {
 		return nil, fmt.Errorf("simulated config load failure")
@@ -313,3 +327,51 @@ func TestIsLinkAccessible_RequestFails(t *testing.T) {
 		t.Errorf("Expected log message for request failure, got: %s", logged)
 	}
 }
+
+func TestAnalyzePage_BotProtectionFallsBackToRender(t *testing.T) {
+	testutil.AllowLoopback(t)
+	originalRender := helpers.FetchRenderedDOMContext
+	helpers.FetchRenderedDOMContext = func(ctx context.Context, url string, opts helpers.FetchOptions) ([]byte, error) {
+		return []byte("<html><title>Rendered Fallback</title></html>"), nil
+	}
+	defer func() { helpers.FetchRenderedDOMContext = originalRender }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html><title>Just a moment...</title></html>"))
+	}))
+	defer server.Close()
+
+	result, err := AnalyzePage(server.URL)
+	if err != nil {
+		t.Fatalf("AnalyzePage failed: %v", err)
+	}
+
+	assertEqual(t, "Title", result.Title, "Rendered Fallback")
+	assertEqual(t, "Rendered", result.Rendered, true)
+	if result.BotProtection == nil || result.BotProtection.Vendor != "Cloudflare" {
+		t.Errorf("Expected Cloudflare detection, got %+v", result.BotProtection)
+	}
+}
+
+func TestAnalyzePage_RenderFailureIsRenderUnavailable(t *testing.T) {
+	testutil.AllowLoopback(t)
+	renderErr := stderrors.New("connection refused")
+	originalRender := helpers.FetchRenderedDOMContext
+	helpers.FetchRenderedDOMContext = func(ctx context.Context, url string, opts helpers.FetchOptions) ([]byte, error) {
+		return nil, renderErr
+	}
+	defer func() { helpers.FetchRenderedDOMContext = originalRender }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><title>Page</title></html>"))
+	}))
+	defer server.Close()
+
+	_, err := Analyze(context.Background(), server.URL, AnalyzeOptions{RenderMode: RenderAlways, SkipLinkCheck: true})
+	var httpErr *errors.HTTPError
+	if !stderrors.Is(err, errors.ErrRenderUnavailable) || !stderrors.Is(err, renderErr) || !stderrors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a 502 render_unavailable error wrapping the cause, got: %v", err)
+	}
+}
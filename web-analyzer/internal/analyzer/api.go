@@ -0,0 +1,57 @@
+package analyzer
+
+import "web-analyzer/pkg/api"
+
+// ToAPI converts r to the /api/v1 schema. Empty lists become [] rather than
+// null.
+func (r *Result) ToAPI() *api.Result {
+	out := &api.Result{
+		PageURL:            r.PageURL,
+		FinalURL:           r.FinalURL,
+		Redirects:          []api.Redirect{},
+		ClientRedirects:    []api.ClientRedirect{},
+		HTMLVersion:        r.HTMLVersion,
+		Title:              r.Title,
+		Headings:           []api.Heading{},
+		InternalLinks:      linksToAPI(r.InternalLinks),
+		ExternalLinks:      linksToAPI(r.ExternalLinks),
+		AccessibleLinks:    linksToAPI(r.AccessibleLinks),
+		InaccessibleLinks:  linksToAPI(r.InaccessibleLinks),
+		HasLoginForm:       r.HasLoginForm,
+		MixedContent:       []api.MixedContent{},
+		Rendered:           r.Rendered,
+		BodyTruncated:      r.BodyTruncated,
+		Revalidated:        r.Revalidated,
+		LinkCache:          api.LinkCacheStats{Hits: r.LinkCache.Hits, Misses: r.LinkCache.Misses},
+		Partial:            r.Partial,
+		Skipped:            []api.Skipped{},
+		AnalysisDurationMs: r.AnalysisDuration.Milliseconds(),
+	}
+	for _, hop := range r.Redirects {
+		out.Redirects = append(out.Redirects, api.Redirect{URL: hop.URL, StatusCode: hop.StatusCode, Location: hop.Location, Permanent: hop.Permanent})
+	}
+	for _, cr := range r.ClientRedirects {
+		out.ClientRedirects = append(out.ClientRedirects, api.ClientRedirect{Type: cr.Type, URL: cr.URL, DelayMs: int64(cr.Delay) * 1000})
+	}
+	for _, h := range r.Headings {
+		out.Headings = append(out.Headings, api.Heading{Tag: h.Tag, Text: h.Title})
+	}
+	for _, mc := range r.MixedContent {
+		out.MixedContent = append(out.MixedContent, api.MixedContent{URL: mc.URL, Element: mc.Element, Attribute: mc.Attribute, Type: mc.Type})
+	}
+	if bp := r.BotProtection; bp != nil {
+		out.BotProtection = &api.BotProtection{Vendor: bp.Vendor, Reason: bp.Reason}
+	}
+	for _, s := range r.Skipped {
+		out.Skipped = append(out.Skipped, api.Skipped{Part: s.Part, Budget: s.Budget, Detail: s.Detail})
+	}
+	return out
+}
+
+func linksToAPI(links []NamedLink) []api.Link {
+	out := make([]api.Link, 0, len(links))
+	for _, l := range links {
+		out = append(out, api.Link{URL: l.URL, Label: l.Label, Occurrences: l.Occurrence})
+	}
+	return out
+}
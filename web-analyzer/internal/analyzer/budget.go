@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"web-analyzer/internal/metrics"
+)
+
+// Parts of an analysis that a budget can cut short.
+const (
+	PartRender    = "render"
+	PartDOM       = "dom"
+	PartLinkCheck = "link_check"
+)
+
+// Budgets that bound a single analysis.
+const (
+	BudgetMaxLinks      = "max_links"
+	BudgetMaxDuration   = "max_duration"
+	BudgetMaxDOMNodes   = "max_dom_nodes"
+	BudgetMaxRenderTime = "max_render_time"
+)
+
+// Skipped records a part of the analysis that was skipped or truncated because
+// a budget ran out.
+type Skipped struct {
+	Part   string
+	Budget string
+	Detail string
+}
+
+// Context causes that mark a deadline as a spent budget rather than a caller
+// cancellation, so the analysis can degrade instead of failing.
+var (
+	errDurationBudget = stderrors.New("analysis wall-time budget exhausted")
+	errRenderBudget   = stderrors.New("render time budget exhausted")
+)
+
+// budgetExhausted reports whether ctx ended because of the given budget.
+func budgetExhausted(ctx context.Context, budget error) bool {
+	return ctx.Err() != nil && stderrors.Is(context.Cause(ctx), budget)
+}
+
+// skip marks the result partial and records what was cut short and why.
+func (r *Result) skip(part, budget, format string, args ...interface{}) {
+	r.Partial = true
+	r.Skipped = append(r.Skipped, Skipped{Part: part, Budget: budget, Detail: fmt.Sprintf(format, args...)})
+}
+
+// recordBudgets counts the budgets exhausted in skipped.
+func recordBudgets(m *metrics.Analyzer, skipped []Skipped) {
+	for _, s := range skipped {
+		m.BudgetsExhausted.Inc(s.Budget)
+	}
+}
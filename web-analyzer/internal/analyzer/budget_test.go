@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/testutil"
+)
+
+func findSkipped(result *Result, budget string) *Skipped {
+	for i := range result.Skipped {
+		if result.Skipped[i].Budget == budget {
+			return &result.Skipped[i]
+		}
+	}
+	return nil
+}
+
+func TestAnalyze_MaxLinksBudget(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var page strings.Builder
+	page.WriteString("<html><title>Many links</title>")
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&page, `<a href="/page%d">p%d</a>`, i, i)
+	}
+	ts := newTestServer(page.String())
+	defer ts.Close()
+
+	result, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{MaxLinks: 2})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if checked := len(result.AccessibleLinks) + len(result.InaccessibleLinks); checked != 2 {
+		t.Errorf("Expected 2 links checked, got %d", checked)
+	}
+	skipped := findSkipped(result, BudgetMaxLinks)
+	if !result.Partial || skipped == nil || skipped.Part != PartLinkCheck || skipped.Detail != "checked 2 of 5 links" {
+		t.Errorf("Expected max_links skip, got partial=%v %+v", result.Partial, result.Skipped)
+	}
+}
+
+func TestAnalyze_MaxDOMNodesBudget(t *testing.T) {
+	testutil.AllowLoopback(t)
+	ts := newTestServer(`<html><head><title>Deep</title></head><body>
+		<h1>First</h1><p>a</p><p>b</p><p>c</p><h2>Late heading</h2><a href="/late">late</a></body></html>`)
+	defer ts.Close()
+
+	// html, head, title, body, h1 fit; everything after is skipped
+	result, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{MaxDOMNodes: 5, SkipLinkCheck: true})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	assertEqual(t, "Title", result.Title, "Deep")
+	assertEqual(t, "Headings", len(result.Headings), 1)
+	assertNamedLinksCount(t, "InternalLinks", result.InternalLinks, 0)
+	if skipped := findSkipped(result, BudgetMaxDOMNodes); !result.Partial || skipped == nil || skipped.Part != PartDOM {
+		t.Errorf("Expected max_dom_nodes skip, got %+v", result.Skipped)
+	}
+
+	// A page that fits exactly is not flagged
+	result, err = Analyze(context.Background(), ts.URL, AnalyzeOptions{MaxDOMNodes: 10, SkipLinkCheck: true})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Partial || len(result.Skipped) != 0 {
+		t.Errorf("Expected complete result, got %+v", result.Skipped)
+	}
+}
+
+func TestAnalyze_MaxDurationBudgetReturnsPartial(t *testing.T) {
+	testutil.AllowLoopback(t)
+	slow := newSlowServer(5 * time.Second)
+	defer slow.Close()
+	ts := newTestServer(`<html><title>Slow links</title><a href="` + slow.URL + `/a">a</a><a href="` + slow.URL + `/b">b</a></html>`)
+	defer ts.Close()
+
+	start := time.Now()
+	result, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{MaxDuration: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected partial result, got error: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Expected analysis to stop at the budget, took %v", time.Since(start))
+	}
+	skipped := findSkipped(result, BudgetMaxDuration)
+	if !result.Partial || skipped == nil || !strings.HasPrefix(skipped.Detail, "2 of 2 links left unchecked") {
+		t.Errorf("Expected max_duration skip for both links, got %+v", result.Skipped)
+	}
+}
+
+func TestAnalyze_RenderBudgetFallsBackToFetchedHTML(t *testing.T) {
+	testutil.AllowLoopback(t)
+	originalRender := helpers.FetchRenderedDOMContext
+	helpers.FetchRenderedDOMContext = func(ctx context.Context, url string, opts helpers.FetchOptions) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	defer func() { helpers.FetchRenderedDOMContext = originalRender }()
+
+	ts := newTestServer("<html><title>Static</title></html>")
+	defer ts.Close()
+
+	result, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{
+		RenderMode:    RenderAlways,
+		RenderTimeout: 100 * time.Millisecond,
+		SkipLinkCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("Expected fallback to fetched HTML, got error: %v", err)
+	}
+	assertEqual(t, "Title", result.Title, "Static")
+	assertEqual(t, "Rendered", result.Rendered, false)
+	if skipped := findSkipped(result, BudgetMaxRenderTime); !result.Partial || skipped == nil || skipped.Part != PartRender {
+		t.Errorf("Expected max_render_time skip, got %+v", result.Skipped)
+	}
+}
+
+func TestAnalyze_CallerCancellationIsNotABudget(t *testing.T) {
+	testutil.AllowLoopback(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := Analyze(ctx, slow.URL, AnalyzeOptions{}); err == nil {
+		t.Error("Expected the caller's deadline during fetch to fail the analysis")
+	}
+}
+
+func TestAnalyzeOptions_BudgetLimits(t *testing.T) {
+	for _, opts := range []AnalyzeOptions{
+		{MaxLinks: 1_000_000},
+		{MaxDOMNodes: 10_000_000},
+		{MaxDuration: time.Hour},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
@@ -1,36 +1,161 @@
 package analyzer
 
 import (
+	"container/list"
 	"sync"
 	"time"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/metrics"
 )
 
+// CacheConfig controls the shared result cache.
+type CacheConfig struct {
+	MaxEntries int           // least recently used results are evicted beyond this
+	TTL        time.Duration // results older than this are not served
+
+	// StripTrackingParams drops utm_* and click-id parameters from cache
+	// keys, so tagged links share the untagged page's entry.
+	StripTrackingParams bool
+
+	Entries *metrics.Gauge // reports the number of stored results; nil = metrics.DefaultAnalyzer.CacheEntries
+}
+
 type cacheEntry struct {
+	key       string
 	Result    *Result
 	Timestamp time.Time
 }
 
-var (
-	cache     = make(map[string]cacheEntry)
-	cacheLock sync.RWMutex
-	cacheTTL  = 10 * time.Minute
-)
+// ResultCache is a size-bounded LRU of analysis results, safe for concurrent
+// use. The package-level cache functions share one; an embedder may hold its own.
+type ResultCache struct {
+	cfg CacheConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+// NewResultCache returns an empty cache. Zero fields take the defaults from
+// constants.
+func NewResultCache(cfg CacheConfig) *ResultCache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = constants.CacheMaxEntries
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = constants.CacheTTL
+	}
+	if cfg.Entries == nil {
+		cfg.Entries = metrics.DefaultAnalyzer.CacheEntries
+	}
+	cfg.Entries.Set(0)
+	return &ResultCache{cfg: cfg, now: time.Now, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+var cache = NewResultCache(CacheConfig{})
+
+// ConfigureCache replaces the shared cache, dropping its entries. Zero
+// fields take the defaults from constants.
+func ConfigureCache(cfg CacheConfig) {
+	cache = NewResultCache(cfg)
+}
+
+// CacheKey returns the cache key for pageURL analyzed with opts: the
+// normalized URL plus the options fingerprint.
+func CacheKey(pageURL string, opts AnalyzeOptions) string {
+	return cache.Key(pageURL, opts)
+}
+
+func GetFromCache(key string) (*Result, bool) {
+	return cache.Get(key)
+}
+
+// GetStaleFromCache returns an expired result that carries validators, so it
+// can be revalidated instead of analyzed again.
+func GetStaleFromCache(key string) (*Result, bool) {
+	return cache.Stale(key)
+}
+
+func StoreInCache(key string, res *Result) {
+	cache.Store(key, res)
+}
 
-func GetFromCache(url string) (*Result, bool) {
-	cacheLock.RLock()
-	defer cacheLock.RUnlock()
-	entry, ok := cache[url]
-	if !ok || time.Since(entry.Timestamp) > cacheTTL {
+// Key returns the cache key for pageURL analyzed with opts.
+func (c *ResultCache) Key(pageURL string, opts AnalyzeOptions) string {
+	key := helpers.NormalizeURL(pageURL)
+	if c.cfg.StripTrackingParams {
+		key = helpers.StripTrackingParams(key)
+	}
+	if fp := opts.Fingerprint(); fp != "" {
+		key += "#" + fp
+	}
+	return key
+}
+
+// Get returns the fresh result stored under key.
+func (c *ResultCache) Get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if c.now().Sub(entry.Timestamp) > c.cfg.TTL {
+		// Entries with validators stay until evicted so they can be revalidated
+		if entry.Result.Validators == nil {
+			c.removeLocked(el)
+		}
 		return nil, false
 	}
+	c.order.MoveToFront(el)
 	return entry.Result, true
 }
 
-func StoreInCache(url string, res *Result) {
-	cacheLock.Lock()
-	defer cacheLock.Unlock()
-	cache[url] = cacheEntry{
-		Result:    res,
-		Timestamp: time.Now(),
+// Stale returns an expired result stored under key that carries validators.
+func (c *ResultCache) Stale(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
 	}
+	entry := el.Value.(*cacheEntry)
+	if c.now().Sub(entry.Timestamp) <= c.cfg.TTL || entry.Result.Validators == nil {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// Store saves res under key, evicting the least recently used results beyond
+// the size limit.
+func (c *ResultCache) Store(key string, res *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value = &cacheEntry{key: key, Result: res, Timestamp: c.now()}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, Result: res, Timestamp: c.now()})
+	for c.order.Len() > c.cfg.MaxEntries {
+		c.removeLocked(c.order.Back())
+	}
+	c.cfg.Entries.Set(float64(c.order.Len()))
+}
+
+func (c *ResultCache) removeLocked(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+	c.cfg.Entries.Set(float64(c.order.Len()))
+}
+
+// Len returns the number of stored results, fresh or expired.
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
 }
@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewResultCache(CacheConfig{MaxEntries: 2, TTL: time.Minute})
+	a, b, d := &Result{Title: "a"}, &Result{Title: "b"}, &Result{Title: "d"}
+	c.Store("a", a)
+	c.Store("b", b)
+	c.Get("a") // a is now more recent than b
+	c.Store("d", d)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if got, ok := c.Get("a"); !ok || got != a {
+		t.Error("Expected a to be kept")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestResultCache_ExpiresAfterTTL(t *testing.T) {
+	c := NewResultCache(CacheConfig{MaxEntries: 10, TTL: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Store("a", &Result{})
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Expected a fresh entry")
+	}
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected the entry to expire")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, got %d entries", c.Len())
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	defer ConfigureCache(CacheConfig{})
+
+	same := []string{"https://x.com", "https://x.com/", "HTTPS://X.COM", "https://x.com:443/#top"}
+	for _, u := range same[1:] {
+		if CacheKey(u, AnalyzeOptions{}) != CacheKey(same[0], AnalyzeOptions{}) {
+			t.Errorf("Expected %q to share the key of %q", u, same[0])
+		}
+	}
+	if CacheKey("https://x.com/Page", AnalyzeOptions{}) == CacheKey("https://x.com/page", AnalyzeOptions{}) {
+		t.Error("Expected path case to be preserved")
+	}
+	if CacheKey("https://x.com/", AnalyzeOptions{}) == CacheKey("https://x.com/", AnalyzeOptions{RenderMode: RenderAlways}) {
+		t.Error("Expected options to change the key")
+	}
+
+	tagged := "https://x.com/?utm_source=mail"
+	if CacheKey(tagged, AnalyzeOptions{}) == CacheKey("https://x.com/", AnalyzeOptions{}) {
+		t.Error("Expected tracking parameters to be kept by default")
+	}
+	ConfigureCache(CacheConfig{StripTrackingParams: true})
+	if CacheKey(tagged, AnalyzeOptions{}) != CacheKey("https://x.com/", AnalyzeOptions{}) {
+		t.Error("Expected tracking parameters to be stripped")
+	}
+}
@@ -11,7 +11,11 @@ type TagConfig struct {
 	AllowedTags []string `json:"allowedTags"`
 }
 
-var LoadTagConfig = func() (*TagConfig, error) {
+// LoadTagConfig loads the tag config for every analysis; tests swap it out.
+var LoadTagConfig = EmbeddedTagConfig
+
+// EmbeddedTagConfig parses the config.json shipped in pkg/embed.
+func EmbeddedTagConfig() (*TagConfig, error) {
 	data, err := embed.LoadEmbeddedConfigFile("config.json")
 	if err != nil {
 		return nil, err
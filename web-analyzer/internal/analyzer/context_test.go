@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/testutil"
+	"web-analyzer/pkg/errors"
+)
+
+func newSlowServer(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("<html><title>Slow</title></html>"))
+	}))
+}
+
+func TestAnalyzePageContext_Cancelled(t *testing.T) {
+	testutil.AllowLoopback(t)
+	ts := newSlowServer(5 * time.Second)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := AnalyzePageContext(ctx, ts.URL)
+	httpErr, ok := err.(*errors.HTTPError)
+	if !ok || httpErr.StatusCode != constants.StatusClientClosedRequest {
+		t.Errorf("Expected client-closed error, got: %v", err)
+	}
+}
+
+func TestAnalyzePageContext_Deadline(t *testing.T) {
+	testutil.AllowLoopback(t)
+	ts := newSlowServer(5 * time.Second)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := AnalyzePageContext(ctx, ts.URL)
+	httpErr, ok := err.(*errors.HTTPError)
+	if !ok || httpErr.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Expected gateway timeout error, got: %v", err)
+	}
+}
+
+func TestClassifyLinksConcurrentlyContext_Partial(t *testing.T) {
+	testutil.AllowLoopback(t)
+	fast := newTestServer("ok")
+	defer fast.Close()
+	slow := newSlowServer(5 * time.Second)
+	defer slow.Close()
+
+	links := []NamedLink{{URL: fast.URL}, {URL: slow.URL}}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	accessible, inaccessible, err := ClassifyLinksConcurrentlyContext(ctx, links, LinkCheckerConfig{
+		MaxConcurrency: 2,
+		Timeout:        10 * time.Second,
+	})
+
+	if !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline error, got: %v", err)
+	}
+	if len(accessible) != 1 || accessible[0].URL != fast.URL {
+		t.Errorf("Expected only the fast link to be accessible, got %+v", accessible)
+	}
+	if len(inaccessible) != 0 {
+		t.Errorf("Expected unchecked links to be left out, got %+v", inaccessible)
+	}
+}
@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/testutil"
+)
+
+// authRecorder remembers the credentials seen on each request path.
+type authRecorder struct {
+	mu   sync.Mutex
+	seen map[string]string
+}
+
+func (a *authRecorder) record(r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cookie, _ := r.Cookie("session")
+	value := r.Header.Get("Authorization")
+	if cookie != nil {
+		value += "|session=" + cookie.Value
+	}
+	a.seen[r.Host+r.URL.Path] = value
+}
+
+func (a *authRecorder) get(key string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seen[key]
+}
+
+func TestAnalyze_CredentialsStayOnOrigin(t *testing.T) {
+	testutil.AllowLoopback(t)
+	rec := &authRecorder{seen: make(map[string]string)}
+
+	thirdParty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r)
+	}))
+	defer thirdParty.Close()
+
+	var site *httptest.Server
+	site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r)
+		switch r.URL.Path {
+		case "/":
+			if r.Header.Get("Authorization") != "Bearer s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`<html><title>Dashboard</title>
+				<a href="/settings">Settings</a>
+				<a href="/leave">Leave</a>
+				<a href="` + thirdParty.URL + `/tracker">Tracker</a></html>`))
+		case "/leave":
+			http.Redirect(w, r, thirdParty.URL+"/landing", http.StatusFound)
+		}
+	}))
+	defer site.Close()
+
+	result, err := Analyze(context.Background(), site.URL+"/", AnalyzeOptions{
+		Credentials: &helpers.Credentials{
+			BearerToken:  "s3cret",
+			CookieHeader: "session=abc",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	assertEqual(t, "Title", result.Title, "Dashboard")
+
+	siteHost := strings.TrimPrefix(site.URL, "http://")
+	thirdHost := strings.TrimPrefix(thirdParty.URL, "http://")
+	assertEqual(t, "page fetch", rec.get(siteHost+"/"), "Bearer s3cret|session=abc")
+	assertEqual(t, "same-origin link", rec.get(siteHost+"/settings"), "Bearer s3cret|session=abc")
+	assertEqual(t, "third-party link", rec.get(thirdHost+"/tracker"), "")
+	assertEqual(t, "redirect to third party", rec.get(thirdHost+"/landing"), "")
+}
+
+func TestAnalyze_URLCredentialsAreNotEchoed(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte("<html><title>Private</title></html>"))
+	}))
+	defer ts.Close()
+
+	withUser := strings.Replace(ts.URL, "http://", "http://admin:hunter2@", 1)
+	result, err := Analyze(context.Background(), withUser, AnalyzeOptions{SkipLinkCheck: true})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if auth == "" {
+		t.Error("Expected URL credentials to be sent as basic auth")
+	}
+	if strings.Contains(result.PageURL, "hunter2") || strings.Contains(result.FinalURL, "hunter2") {
+		t.Errorf("Expected credentials to be stripped from result URLs, got %s / %s", result.PageURL, result.FinalURL)
+	}
+}
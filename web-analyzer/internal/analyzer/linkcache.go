@@ -0,0 +1,128 @@
+package analyzer
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"web-analyzer/internal/constants"
+)
+
+// LinkCacheConfig controls the link-status cache shared by all analyses.
+type LinkCacheConfig struct {
+	SuccessTTL time.Duration // how long an accessible link is trusted
+	FailureTTL time.Duration // how long an inaccessible link is trusted; usually shorter
+	MaxEntries int
+}
+
+// LinkCacheStats counts link checks answered from the link-status cache.
+type LinkCacheStats struct {
+	Hits   int
+	Misses int
+}
+
+type linkStatus struct {
+	accessible bool
+	expires    time.Time
+}
+
+// LinkStatusCache remembers link-check outcomes by URL so shared navigation
+// and footer links are not checked again by every analysis. It is safe for
+// concurrent use.
+type LinkStatusCache struct {
+	cfg LinkCacheConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]linkStatus
+}
+
+// NewLinkStatusCache returns an empty cache. Zero fields take the defaults
+// from constants.
+func NewLinkStatusCache(cfg LinkCacheConfig) *LinkStatusCache {
+	if cfg.SuccessTTL <= 0 {
+		cfg.SuccessTTL = constants.LinkCacheSuccessTTL
+	}
+	if cfg.FailureTTL <= 0 {
+		cfg.FailureTTL = constants.LinkCacheFailureTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = constants.LinkCacheMaxEntries
+	}
+	return &LinkStatusCache{cfg: cfg, now: time.Now, entries: make(map[string]linkStatus)}
+}
+
+var linkCache = NewLinkStatusCache(LinkCacheConfig{})
+
+// ConfigureLinkCache replaces the shared link-status cache, dropping its
+// entries. Zero fields take the defaults from constants.
+func ConfigureLinkCache(cfg LinkCacheConfig) {
+	linkCache = NewLinkStatusCache(cfg)
+}
+
+// linkCacheKey is the cache key for link, or "" when the outcome is private
+// to the caller because credentials are sent with same-origin links. Anything
+// else that can change the outcome is part of the key: the proxy, which may
+// see a different network, the user agent and headers, which servers may
+// answer differently, and the timeout.
+func linkCacheKey(link string, config LinkCheckerConfig) string {
+	if config.Credentials != nil {
+		return ""
+	}
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s|%s|%s|", config.Proxy, config.UserAgent, config.Timeout)
+	names := slices.Sorted(maps.Keys(config.Headers))
+	for _, name := range names {
+		fmt.Fprintf(&key, "%s=%s;", http.CanonicalHeaderKey(name), config.Headers[name])
+	}
+	key.WriteString("|" + link)
+	return key.String()
+}
+
+func (c *LinkStatusCache) get(key string) (accessible, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status, ok := c.entries[key]
+	if !ok {
+		return false, false
+	}
+	if c.now().After(status.expires) {
+		delete(c.entries, key)
+		return false, false
+	}
+	return status.accessible, true
+}
+
+func (c *LinkStatusCache) store(key string, accessible bool) {
+	ttl := c.cfg.FailureTTL
+	if accessible {
+		ttl = c.cfg.SuccessTTL
+	}
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.cfg.MaxEntries {
+		c.evictLocked(now)
+	}
+	c.entries[key] = linkStatus{accessible: accessible, expires: now.Add(ttl)}
+}
+
+// evictLocked drops expired entries, then arbitrary ones until there is room.
+func (c *LinkStatusCache) evictLocked(now time.Time) {
+	for key, status := range c.entries {
+		if now.After(status.expires) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.cfg.MaxEntries {
+			break
+		}
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/testutil"
+)
+
+func TestClassifyLinks_UsesLinkStatusCache(t *testing.T) {
+	testutil.AllowLoopback(t)
+	defer ConfigureLinkCache(LinkCacheConfig{})
+	ConfigureLinkCache(LinkCacheConfig{SuccessTTL: time.Hour, FailureTTL: time.Minute})
+	now := time.Now()
+	linkCache.now = func() time.Time { return now }
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	links := []NamedLink{{URL: ts.URL + "/ok"}, {URL: ts.URL + "/missing"}}
+	config := LinkCheckerConfig{MaxConcurrency: 2, Timeout: time.Second}
+
+	_, _, stats, _ := classifyLinks(context.Background(), links, config, linkCache)
+	if stats != (LinkCacheStats{Misses: 2}) || requests.Load() != 2 {
+		t.Fatalf("Expected two checks on a cold cache, got %+v after %d requests", stats, requests.Load())
+	}
+
+	accessible, inaccessible, stats, _ := classifyLinks(context.Background(), links, config, linkCache)
+	if stats != (LinkCacheStats{Hits: 2}) || requests.Load() != 2 {
+		t.Errorf("Expected both outcomes from the cache, got %+v after %d requests", stats, requests.Load())
+	}
+	if len(accessible) != 1 || len(inaccessible) != 1 {
+		t.Errorf("Expected cached outcomes to be kept, got %d accessible, %d inaccessible", len(accessible), len(inaccessible))
+	}
+
+	// Failures expire sooner than successes
+	now = now.Add(2 * time.Minute)
+	_, _, stats, _ = classifyLinks(context.Background(), links, config, linkCache)
+	if stats != (LinkCacheStats{Hits: 1, Misses: 1}) || requests.Load() != 3 {
+		t.Errorf("Expected only the failure to be rechecked, got %+v after %d requests", stats, requests.Load())
+	}
+
+	// A refresh checks every link again and stores the fresh outcomes
+	config.SkipCache = true
+	_, _, stats, _ = classifyLinks(context.Background(), links, config, linkCache)
+	if stats != (LinkCacheStats{Misses: 2}) || requests.Load() != 5 {
+		t.Errorf("Expected SkipCache to check both links, got %+v after %d requests", stats, requests.Load())
+	}
+	config.SkipCache = false
+
+	// Outcomes are not shared across user agents, headers or timeouts
+	for _, vary := range []func(*LinkCheckerConfig){
+		func(c *LinkCheckerConfig) { c.UserAgent = "other-bot" },
+		func(c *LinkCheckerConfig) { c.Headers = map[string]string{"X-Tenant": "b"} },
+		func(c *LinkCheckerConfig) { c.Timeout = 2 * time.Second },
+	} {
+		varied := config
+		vary(&varied)
+		before := requests.Load()
+		_, _, stats, _ = classifyLinks(context.Background(), links, varied, linkCache)
+		if stats != (LinkCacheStats{Misses: 2}) || requests.Load() != before+2 {
+			t.Errorf("Expected a varied config to miss the cache, got %+v", stats)
+		}
+	}
+
+	// Authenticated checks bypass the cache
+	config.Credentials = &helpers.Credentials{BearerToken: "secret"}
+	_, _, stats, _ = classifyLinks(context.Background(), links, config, linkCache)
+	if stats != (LinkCacheStats{}) || requests.Load() != 13 {
+		t.Errorf("Expected authenticated checks to skip the cache, got %+v after %d requests", stats, requests.Load())
+	}
+}
+
+func TestLinkStatusCache_Bounded(t *testing.T) {
+	c := NewLinkStatusCache(LinkCacheConfig{MaxEntries: 2})
+	c.store("a", true)
+	c.store("b", true)
+	c.store("c", false)
+	if len(c.entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(c.entries))
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Error("Expected the newest entry to be kept")
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"web-analyzer/internal/testutil"
 )
 
 func TestIsLinkAccessible_ValidAndInvalid(t *testing.T) {
+	testutil.AllowLoopback(t)
 	// ✅ Working server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
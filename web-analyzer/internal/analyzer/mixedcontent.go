@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// MixedContentActive marks insecure resources that can alter the page (scripts, styles, frames, form targets).
+	MixedContentActive = "active"
+	// MixedContentPassive marks insecure resources that are only displayed (images, audio, video).
+	MixedContentPassive = "passive"
+)
+
+// MixedContent is an http:// subresource referenced from an HTTPS page.
+type MixedContent struct {
+	URL       string
+	Element   string
+	Attribute string
+	Type      string
+}
+
+// mixedContentSources lists, per element, the attributes that load a subresource
+// and whether a plain-HTTP load counts as active or passive mixed content.
+var mixedContentSources = map[string]map[string]string{
+	"script": {"src": MixedContentActive},
+	"iframe": {"src": MixedContentActive},
+	"frame":  {"src": MixedContentActive},
+	"object": {"data": MixedContentActive},
+	"embed":  {"src": MixedContentActive},
+	"form":   {"action": MixedContentActive},
+	"button": {"formaction": MixedContentActive},
+	"img":    {"src": MixedContentPassive, "srcset": MixedContentPassive},
+	"audio":  {"src": MixedContentPassive},
+	"video":  {"src": MixedContentPassive, "poster": MixedContentPassive},
+	"source": {"src": MixedContentPassive, "srcset": MixedContentPassive},
+	"track":  {"src": MixedContentPassive},
+}
+
+// linkRelTypes classifies <link rel=...> values that fetch a subresource.
+// Navigational relations such as canonical or alternate are not loaded and are ignored.
+var linkRelTypes = map[string]string{
+	"stylesheet":       MixedContentActive,
+	"preload":          MixedContentActive,
+	"modulepreload":    MixedContentActive,
+	"manifest":         MixedContentActive,
+	"icon":             MixedContentPassive,
+	"apple-touch-icon": MixedContentPassive,
+}
+
+// findMixedContent returns the insecure subresources referenced by a single element node.
+func findMixedContent(n *html.Node, baseURL *url.URL) []MixedContent {
+	attrs := mixedContentSources[n.Data]
+	if n.Data == "link" {
+		attrs = linkMixedContentAttrs(n)
+	}
+	if attrs == nil {
+		return nil
+	}
+
+	var found []MixedContent
+	for _, attr := range n.Attr {
+		kind, ok := attrs[attr.Key]
+		if !ok {
+			continue
+		}
+		refs := []string{attr.Val}
+		if attr.Key == "srcset" {
+			refs = parseSrcset(attr.Val)
+		}
+		for _, ref := range refs {
+			ref = strings.TrimSpace(ref)
+			if ref == "" {
+				continue
+			}
+			parsed, err := url.Parse(ref)
+			if err != nil {
+				continue
+			}
+			resolved := baseURL.ResolveReference(parsed)
+			if !strings.EqualFold(resolved.Scheme, "http") {
+				continue
+			}
+			found = append(found, MixedContent{
+				URL:       resolved.String(),
+				Element:   n.Data,
+				Attribute: attr.Key,
+				Type:      kind,
+			})
+		}
+	}
+	return found
+}
+
+// linkMixedContentAttrs picks the strongest classification among a <link>'s rel tokens.
+func linkMixedContentAttrs(n *html.Node) map[string]string {
+	kind := ""
+	for _, attr := range n.Attr {
+		if attr.Key != "rel" {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.ToLower(attr.Val)) {
+			switch linkRelTypes[rel] {
+			case MixedContentActive:
+				kind = MixedContentActive
+			case MixedContentPassive:
+				if kind == "" {
+					kind = MixedContentPassive
+				}
+			}
+		}
+	}
+	if kind == "" {
+		return nil
+	}
+	return map[string]string{"href": kind}
+}
+
+// parseSrcset extracts the candidate URLs from a srcset attribute value.
+func parseSrcset(val string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(val, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const mixedContentHTML = `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<link rel="stylesheet" href="http://cdn.example.com/site.css">
+		<link rel="icon" href="http://cdn.example.com/favicon.ico">
+		<link rel="canonical" href="http://example.com/page">
+		<script src="http://cdn.example.com/app.js"></script>
+		<script src="/secure.js"></script>
+	</head>
+	<body>
+		<img src="http://img.example.com/a.png" srcset="http://img.example.com/a-2x.png 2x, https://img.example.com/a-3x.png 3x">
+		<iframe src="//frames.example.com/embed"></iframe>
+		<form action="http://example.com/login"><input type="password"></form>
+		<video poster="https://img.example.com/poster.png"><source src="http://media.example.com/v.mp4"></video>
+	</body>
+	</html>
+`
+
+func extractFrom(t *testing.T, page, base string) *Result {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	baseURL, _ := url.Parse(base)
+	result := &Result{}
+	extractInfo(context.Background(), doc, baseURL, result, 0)
+	return result
+}
+
+func TestExtractInfo_MixedContentOnHTTPS(t *testing.T) {
+	result := extractFrom(t, mixedContentHTML, "https://example.com/")
+
+	want := []MixedContent{
+		{URL: "http://cdn.example.com/site.css", Element: "link", Attribute: "href", Type: MixedContentActive},
+		{URL: "http://cdn.example.com/favicon.ico", Element: "link", Attribute: "href", Type: MixedContentPassive},
+		{URL: "http://cdn.example.com/app.js", Element: "script", Attribute: "src", Type: MixedContentActive},
+		{URL: "http://img.example.com/a.png", Element: "img", Attribute: "src", Type: MixedContentPassive},
+		{URL: "http://img.example.com/a-2x.png", Element: "img", Attribute: "srcset", Type: MixedContentPassive},
+		{URL: "http://example.com/login", Element: "form", Attribute: "action", Type: MixedContentActive},
+		{URL: "http://media.example.com/v.mp4", Element: "source", Attribute: "src", Type: MixedContentPassive},
+	}
+
+	if len(result.MixedContent) != len(want) {
+		t.Fatalf("Expected %d mixed content findings, got %d: %+v", len(want), len(result.MixedContent), result.MixedContent)
+	}
+	for i, w := range want {
+		if result.MixedContent[i] != w {
+			t.Errorf("Finding %d: expected %+v, got %+v", i, w, result.MixedContent[i])
+		}
+	}
+}
+
+func TestExtractInfo_NoMixedContentOnHTTP(t *testing.T) {
+	result := extractFrom(t, mixedContentHTML, "http://example.com/")
+	if len(result.MixedContent) != 0 {
+		t.Errorf("Expected no mixed content for an HTTP page, got %+v", result.MixedContent)
+	}
+}
@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/pkg/errors"
+)
+
+// RenderMode controls when a page is rendered through Puppeteer.
+type RenderMode string
+
+const (
+	// RenderAuto renders only when bot protection is detected.
+	RenderAuto RenderMode = "auto"
+	// RenderAlways always analyzes the rendered DOM.
+	RenderAlways RenderMode = "always"
+	// RenderNever analyzes the fetched HTML even when it is a challenge page.
+	RenderNever RenderMode = "never"
+)
+
+// AnalyzeOptions tunes a single analysis. Zero values fall back to the defaults
+// in constants, so the zero AnalyzeOptions is a normal, link-checking analysis.
+type AnalyzeOptions struct {
+	UserAgent       string
+	Headers         map[string]string // sent with the page fetch and render, and with link checks on the page's origin
+	FetchTimeout    time.Duration
+	RenderTimeout   time.Duration
+	LinkTimeout     time.Duration
+	LinkConcurrency int
+	SkipLinkCheck   bool
+	RenderMode      RenderMode
+	Proxy           string // http://, https:// or socks5:// egress for this analysis
+
+	// Budgets; when one runs out the result is returned partial with the
+	// affected parts listed in Result.Skipped. RenderTimeout is the render budget.
+	MaxLinks    int           // links checked, default constants.MaxLinksChecked
+	MaxDuration time.Duration // wall time for the whole analysis, default constants.AnalysisTimeout
+	MaxDOMNodes int           // elements walked, default constants.MaxDOMNodes
+
+	// Credentials are sent only to the page's origin. Authenticated results
+	// are never cached, so they are excluded from the fingerprint.
+	Credentials *helpers.Credentials `json:"-"`
+
+	// SkipLinkCache checks every link over the network instead of trusting
+	// the link-status cache; the fresh outcomes are still stored.
+	SkipLinkCache bool `json:"-"`
+}
+
+// withDefaults fills in every unset field but FetchTimeout, which falls back
+// to the fetch's FetchLimits.ReadTimeout.
+func (o AnalyzeOptions) withDefaults() AnalyzeOptions {
+	if o.UserAgent == "" {
+		o.UserAgent = constants.DefaultUserAgent
+	}
+	if o.RenderTimeout <= 0 {
+		o.RenderTimeout = constants.RenderTimeout
+	}
+	if o.LinkTimeout <= 0 {
+		o.LinkTimeout = constants.LinkCheckTimeout
+	}
+	if o.LinkConcurrency <= 0 {
+		o.LinkConcurrency = constants.LinkCheckConcurrency
+	}
+	if o.RenderMode == "" {
+		o.RenderMode = RenderAuto
+	}
+	if o.MaxLinks <= 0 {
+		o.MaxLinks = constants.MaxLinksChecked
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = constants.AnalysisTimeout
+	}
+	if o.MaxDOMNodes <= 0 {
+		o.MaxDOMNodes = constants.MaxDOMNodes
+	}
+	return o
+}
+
+// Validate rejects options that cannot be honoured.
+func (o AnalyzeOptions) Validate() error {
+	switch o.RenderMode {
+	case "", RenderAuto, RenderAlways, RenderNever:
+	default:
+		return errors.New(errors.CodeInvalidOptions, nil, "invalid render mode %q", o.RenderMode)
+	}
+	if o.LinkConcurrency > constants.MaxLinkCheckConcurrency {
+		return errors.New(errors.CodeInvalidOptions, nil, "link concurrency must be at most %d", constants.MaxLinkCheckConcurrency)
+	}
+	if o.Proxy != "" {
+		if _, err := helpers.ParseProxyURL(o.Proxy); err != nil {
+			return errors.New(errors.CodeInvalidOptions, err, "%v", err)
+		}
+	}
+	if o.FetchTimeout > constants.AnalysisTimeout || o.RenderTimeout > constants.AnalysisTimeout ||
+		o.LinkTimeout > constants.AnalysisTimeout || o.MaxDuration > constants.AnalysisTimeout {
+		return errors.New(errors.CodeInvalidOptions, nil, "timeouts must not exceed %v", constants.AnalysisTimeout)
+	}
+	if o.MaxLinks > constants.MaxLinksChecked {
+		return errors.New(errors.CodeInvalidOptions, nil, "max links must be at most %d", constants.MaxLinksChecked)
+	}
+	if o.MaxDOMNodes > constants.MaxDOMNodes {
+		return errors.New(errors.CodeInvalidOptions, nil, "max DOM nodes must be at most %d", constants.MaxDOMNodes)
+	}
+	return nil
+}
+
+// Fingerprint identifies options that change the analysis outcome, so results
+// produced with different options are not confused. Defaults map to "".
+func (o AnalyzeOptions) Fingerprint() string {
+	o = o.withDefaults()
+	if isDefaultOptions(o) {
+		return ""
+	}
+	data, _ := json.Marshal(o)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func isDefaultOptions(o AnalyzeOptions) bool {
+	d := AnalyzeOptions{}.withDefaults()
+	return o.UserAgent == d.UserAgent && len(o.Headers) == 0 &&
+		o.FetchTimeout == d.FetchTimeout && o.RenderTimeout == d.RenderTimeout &&
+		o.LinkTimeout == d.LinkTimeout && o.LinkConcurrency == d.LinkConcurrency &&
+		o.SkipLinkCheck == d.SkipLinkCheck && o.RenderMode == d.RenderMode && o.Proxy == d.Proxy &&
+		o.MaxLinks == d.MaxLinks && o.MaxDuration == d.MaxDuration && o.MaxDOMNodes == d.MaxDOMNodes
+}
+
+func (o AnalyzeOptions) fetchOptions() helpers.FetchOptions {
+	return helpers.FetchOptions{UserAgent: o.UserAgent, Headers: o.Headers, Timeout: o.FetchTimeout, Credentials: o.Credentials, Proxy: o.Proxy}
+}
+
+func (o AnalyzeOptions) renderOptions() helpers.FetchOptions {
+	return helpers.FetchOptions{UserAgent: o.UserAgent, Headers: o.Headers, Timeout: o.RenderTimeout, Credentials: o.Credentials, Proxy: o.Proxy}
+}
+
+func (o AnalyzeOptions) linkCheckerConfig(pageURL *url.URL) LinkCheckerConfig {
+	return LinkCheckerConfig{
+		MaxConcurrency: o.LinkConcurrency,
+		Timeout:        o.LinkTimeout,
+		UserAgent:      o.UserAgent,
+		Headers:        o.Headers,
+		Credentials:    o.Credentials,
+		SkipCache:      o.SkipLinkCache,
+		PageURL:        pageURL,
+		Proxy:          o.Proxy,
+	}
+}
@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/testutil"
+)
+
+func TestAnalyze_SendsUserAgentAndHeaders(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var pageUA, pageHeader, linkUA, linkHeader, externalHeader string
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		externalHeader = r.Header.Get("X-Audit")
+	}))
+	defer external.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		pageUA, pageHeader = r.UserAgent(), r.Header.Get("X-Audit")
+		w.Write([]byte(`<html><a href="/linked">Linked</a><a href="` + external.URL + `/">External</a></html>`))
+	})
+	mux.HandleFunc("/linked", func(w http.ResponseWriter, r *http.Request) {
+		linkUA, linkHeader = r.UserAgent(), r.Header.Get("X-Audit")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	result, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{
+		UserAgent: "audit-bot/2.0",
+		Headers:   map[string]string{"X-Audit": "yes"},
+	})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	assertEqual(t, "page user agent", pageUA, "audit-bot/2.0")
+	assertEqual(t, "page header", pageHeader, "yes")
+	assertEqual(t, "link user agent", linkUA, "audit-bot/2.0")
+	assertEqual(t, "same-origin link header", linkHeader, "yes")
+	assertEqual(t, "external link header", externalHeader, "")
+	assertNamedLinksCount(t, "AccessibleLinks", result.AccessibleLinks, 2)
+}
+
+func TestAnalyze_DefaultUserAgentAndSkipLinkCheck(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var pageUA string
+	var linkChecks int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		pageUA = r.UserAgent()
+		w.Write([]byte(`<html><a href="/linked">Linked</a></html>`))
+	})
+	mux.HandleFunc("/linked", func(w http.ResponseWriter, r *http.Request) {
+		linkChecks++
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	result, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{SkipLinkCheck: true})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	assertEqual(t, "user agent", pageUA, constants.DefaultUserAgent)
+	assertEqual(t, "link checks", linkChecks, 0)
+	assertNamedLinksCount(t, "InternalLinks", result.InternalLinks, 1)
+	assertNamedLinksCount(t, "AccessibleLinks", result.AccessibleLinks, 0)
+}
+
+func TestAnalyze_RenderModes(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var renders int
+	originalRender := helpers.FetchRenderedDOMContext
+	helpers.FetchRenderedDOMContext = func(ctx context.Context, url string, opts helpers.FetchOptions) ([]byte, error) {
+		renders++
+		if opts.Timeout != 7*time.Second {
+			t.Errorf("Expected render timeout 7s, got %v", opts.Timeout)
+		}
+		return []byte("<html><title>Rendered</title></html>"), nil
+	}
+	defer func() { helpers.FetchRenderedDOMContext = originalRender }()
+
+	challenge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html><title>Just a moment...</title></html>"))
+	}))
+	defer challenge.Close()
+	plain := newTestServer("<html><title>Plain</title></html>")
+	defer plain.Close()
+
+	tests := []struct {
+		name  string
+		url   string
+		mode  RenderMode
+		title string
+	}{
+		{"never keeps the challenge page", challenge.URL, RenderNever, "Just a moment..."},
+		{"auto renders the challenge page", challenge.URL, RenderAuto, "Rendered"},
+		{"auto leaves plain pages alone", plain.URL, RenderAuto, "Plain"},
+		{"always renders plain pages", plain.URL, RenderAlways, "Rendered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Analyze(context.Background(), tt.url, AnalyzeOptions{
+				RenderMode:    tt.mode,
+				RenderTimeout: 7 * time.Second,
+				SkipLinkCheck: true,
+			})
+			if err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+			assertEqual(t, "Title", result.Title, tt.title)
+			assertEqual(t, "Rendered", result.Rendered, tt.title == "Rendered")
+		})
+	}
+	assertEqual(t, "render calls", renders, 2)
+}
+
+func TestAnalyzeOptions_Validate(t *testing.T) {
+	invalid := []AnalyzeOptions{
+		{RenderMode: "sometimes"},
+		{LinkConcurrency: constants.MaxLinkCheckConcurrency + 1},
+		{FetchTimeout: constants.AnalysisTimeout + time.Second},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected validation error for %+v", opts)
+		}
+	}
+	if err := (AnalyzeOptions{RenderMode: RenderNever, LinkConcurrency: 5}).Validate(); err != nil {
+		t.Errorf("Expected valid options, got: %v", err)
+	}
+}
+
+func TestAnalyzeOptions_Fingerprint(t *testing.T) {
+	if fp := (AnalyzeOptions{LinkConcurrency: constants.LinkCheckConcurrency}).Fingerprint(); fp != "" {
+		t.Errorf("Expected default options to have an empty fingerprint, got %q", fp)
+	}
+	a := AnalyzeOptions{UserAgent: "a"}.Fingerprint()
+	b := AnalyzeOptions{UserAgent: "b"}.Fingerprint()
+	if a == "" || a == b {
+		t.Errorf("Expected distinct fingerprints, got %q and %q", a, b)
+	}
+}
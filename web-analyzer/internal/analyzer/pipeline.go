@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/metrics"
+)
+
+// Pipeline holds what an analysis depends on. The package-level Analyze uses
+// the package defaults; a Pipeline lets an embedder supply its own fetcher,
+// renderer, tag config, link-status cache, egress and metrics instead.
+type Pipeline struct {
+	Fetch     func(ctx context.Context, url string, opts helpers.FetchOptions) (*helpers.FetchResult, error)
+	Render    func(ctx context.Context, url string, opts helpers.FetchOptions) ([]byte, error)
+	TagConfig *TagConfig       // nil = LoadTagConfig on every analysis
+	LinkCache *LinkStatusCache // nil = check every link
+
+	Egress     *helpers.Egress      // passed to Fetch, Render and link checks; nil = the process-wide egress
+	LinkClient *http.Client         // sends link checks; nil = a client on Egress
+	Metrics    *metrics.Analyzer    // nil = metrics.DefaultAnalyzer
+	Limits     *helpers.FetchLimits // passed to Fetch and Render; nil = helpers.DefaultFetchLimits
+}
+
+// defaultPipeline is read on every call, so swapped hooks and
+// ConfigureLinkCache apply to the next analysis.
+func defaultPipeline() Pipeline {
+	return Pipeline{
+		Fetch:     helpers.TryStandardFetchContext,
+		Render:    helpers.FetchRenderedDOMContext,
+		LinkCache: linkCache,
+	}
+}
+
+func (p Pipeline) meters() *metrics.Analyzer {
+	if p.Metrics != nil {
+		return p.Metrics
+	}
+	return metrics.DefaultAnalyzer
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	ClientRedirectMetaRefresh = "meta-refresh"
+	ClientRedirectJavaScript  = "javascript"
+)
+
+// ClientRedirect is a redirect the page performs itself after it has loaded.
+type ClientRedirect struct {
+	Type  string
+	URL   string
+	Delay int // seconds, meta refresh only
+}
+
+// jsLocationPatterns match simple string-literal assignments to location.
+var jsLocationPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:\b(?:window|document|self|top)\.)?\blocation(?:\.href)?\s*=\s*["']([^"']+)["']`),
+	regexp.MustCompile(`\blocation\.(?:replace|assign)\(\s*["']([^"']+)["']\s*\)`),
+}
+
+// findMetaRefresh parses <meta http-equiv="refresh" content="N; url=...">.
+func findMetaRefresh(n *html.Node, baseURL *url.URL) *ClientRedirect {
+	var httpEquiv, content string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "http-equiv":
+			httpEquiv = attr.Val
+		case "content":
+			content = attr.Val
+		}
+	}
+	if !strings.EqualFold(httpEquiv, "refresh") {
+		return nil
+	}
+
+	delayPart, target, _ := strings.Cut(content, ";")
+	if target == "" {
+		delayPart, target, _ = strings.Cut(content, ",")
+	}
+	target = strings.TrimSpace(target)
+	if len(target) >= 4 && strings.EqualFold(target[:4], "url=") {
+		target = strings.TrimSpace(target[4:])
+	}
+	target = strings.Trim(target, `"'`)
+	if target == "" {
+		// A bare delay only reloads the current page
+		return nil
+	}
+
+	resolved, ok := resolveRedirect(baseURL, target)
+	if !ok {
+		return nil
+	}
+	delay, _ := strconv.Atoi(strings.TrimSpace(delayPart))
+	return &ClientRedirect{Type: ClientRedirectMetaRefresh, URL: resolved, Delay: delay}
+}
+
+// findJSRedirects scans inline script text for location assignments.
+func findJSRedirects(script string, baseURL *url.URL) []ClientRedirect {
+	var found []ClientRedirect
+	for _, re := range jsLocationPatterns {
+		for _, m := range re.FindAllStringSubmatch(script, -1) {
+			if resolved, ok := resolveRedirect(baseURL, m[1]); ok {
+				found = append(found, ClientRedirect{Type: ClientRedirectJavaScript, URL: resolved})
+			}
+		}
+	}
+	return found
+}
+
+func resolveRedirect(baseURL *url.URL, target string) (string, bool) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+	return baseURL.ResolveReference(parsed).String(), true
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/testutil"
+	"web-analyzer/pkg/errors"
+)
+
+func TestAnalyzePage_FollowsRedirects(t *testing.T) {
+	testutil.AllowLoopback(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/", http.StatusFound)
+	})
+	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><title>Docs</title><a href="intro">Intro</a></html>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	result, err := AnalyzePage(ts.URL + "/old")
+	if err != nil {
+		t.Fatalf("AnalyzePage failed: %v", err)
+	}
+
+	assertEqual(t, "Title", result.Title, "Docs")
+	assertEqual(t, "FinalURL", result.FinalURL, ts.URL+"/docs/")
+	if len(result.Redirects) != 2 {
+		t.Fatalf("Expected 2 redirect hops, got %+v", result.Redirects)
+	}
+	assertEqual(t, "first hop permanent", result.Redirects[0].Permanent, true)
+	assertEqual(t, "first hop location", result.Redirects[0].Location, "/docs")
+	assertEqual(t, "second hop status", result.Redirects[1].StatusCode, http.StatusFound)
+	assertEqual(t, "second hop permanent", result.Redirects[1].Permanent, false)
+
+	// Relative links resolve against the final URL, not the submitted one
+	if len(result.InternalLinks) != 1 || result.InternalLinks[0].URL != ts.URL+"/docs/intro" {
+		t.Errorf("Expected link resolved against final URL, got %+v", result.InternalLinks)
+	}
+}
+
+func TestAnalyzePage_RedirectLoop(t *testing.T) {
+	testutil.AllowLoopback(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	_, err := AnalyzePage(ts.URL + "/a")
+	if err == nil || !strings.Contains(err.Error(), "redirect loop") {
+		t.Errorf("Expected redirect loop error, got: %v", err)
+	}
+}
+
+func TestAnalyzePage_TooManyRedirects(t *testing.T) {
+	testutil.AllowLoopback(t)
+	// Every hop is a new URL, so only the limit stops the chain
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		http.Redirect(w, r, fmt.Sprintf("/%d", n+1), http.StatusFound)
+	}))
+	defer ts.Close()
+
+	_, err := AnalyzePage(ts.URL + "/0")
+	var httpErr *errors.HTTPError
+	if !stderrors.As(err, &httpErr) || httpErr.Code != errors.CodeRedirectLoop || httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Expected a 502 redirect_loop after %d redirects, got %v", constants.MaxRedirects, err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("stopped after %d redirects", constants.MaxRedirects)) {
+		t.Errorf("Expected the limit in the message, got %v", err)
+	}
+}
+
+func TestExtractInfo_ClientRedirects(t *testing.T) {
+	page := `
+		<html><head>
+			<meta http-equiv="refresh" content="5; URL='/moved'">
+			<meta http-equiv="refresh" content="30">
+			<script>if (!ok) { window.location.href = "https://login.example.com/"; }</script>
+			<script>location.replace('/next');</script>
+			<script>if (location.href == "x") {}</script>
+		</head></html>`
+	result := extractFrom(t, page, "https://example.com/page")
+
+	want := []ClientRedirect{
+		{Type: ClientRedirectMetaRefresh, URL: "https://example.com/moved", Delay: 5},
+		{Type: ClientRedirectJavaScript, URL: "https://login.example.com/"},
+		{Type: ClientRedirectJavaScript, URL: "https://example.com/next"},
+	}
+	if len(result.ClientRedirects) != len(want) {
+		t.Fatalf("Expected %d client redirects, got %+v", len(want), result.ClientRedirects)
+	}
+	for i, w := range want {
+		if result.ClientRedirects[i] != w {
+			t.Errorf("Redirect %d: expected %+v, got %+v", i, w, result.ClientRedirects[i])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/metrics"
+)
+
+// Revalidate asks the server whether the page behind an expired cached result
+// has changed, using the validators stored with it. On 304 Not Modified it
+// returns a copy of cached with Revalidated set, rechecking the links when
+// recheckLinks is true. It returns false when the page changed, has no
+// validators or the conditional request failed, and a full analysis is needed.
+func Revalidate(ctx context.Context, cached *Result, opts AnalyzeOptions, recheckLinks bool) (*Result, bool) {
+	if cached.Validators == nil {
+		return nil, false
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, false
+	}
+	opts = opts.withDefaults()
+
+	ctx, cancel := context.WithTimeoutCause(ctx, opts.MaxDuration, errDurationBudget)
+	defer cancel()
+
+	start := time.Now()
+	pageURL := cached.FinalURL
+	if pageURL == "" {
+		pageURL = cached.PageURL
+	}
+	p := defaultPipeline()
+	fetchOpts := opts.fetchOptions()
+	fetchOpts.Egress, fetchOpts.Limits = p.Egress, p.Limits
+	notModified, err := helpers.NotModified(ctx, pageURL, cached.Validators, fetchOpts)
+	p.meters().PhaseDuration.ObserveSince(start, metrics.PhaseFetch)
+	switch {
+	case err != nil:
+		slog.InfoContext(ctx, "revalidation failed", "url", pageURL, "error", err)
+		metrics.CacheRevalidations.Inc(metrics.RevalidationError)
+		return nil, false
+	case !notModified:
+		metrics.CacheRevalidations.Inc(metrics.RevalidationModified)
+		return nil, false
+	}
+	metrics.CacheRevalidations.Inc(metrics.RevalidationNotModified)
+	slog.DebugContext(ctx, "page not modified", "url", pageURL, "recheck_links", recheckLinks)
+
+	result := *cached
+	result.Revalidated = true
+	if recheckLinks && !opts.SkipLinkCheck {
+		result.Skipped = nil
+		opts.SkipLinkCache = true
+		p.checkLinks(ctx, &result, opts)
+	}
+	result.AnalysisDuration = time.Since(start)
+	p.meters().PhaseDuration.Observe(result.AnalysisDuration.Seconds(), metrics.PhaseTotal)
+	return &result, true
+}
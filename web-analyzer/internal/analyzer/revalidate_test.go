@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"web-analyzer/internal/testutil"
+)
+
+func TestRevalidate(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var linkChecks atomic.Int32
+	etag := `"v1"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/link" {
+			linkChecks.Add(1)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`<html><title>Stable</title><a href="/link">link</a></html>`))
+	}))
+	defer ts.Close()
+
+	cached, err := Analyze(context.Background(), ts.URL, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	checksAfterAnalyze := linkChecks.Load()
+
+	result, ok := Revalidate(context.Background(), cached, AnalyzeOptions{}, false)
+	if !ok || !result.Revalidated || result.Title != "Stable" || cached.Revalidated {
+		t.Fatalf("Expected a revalidated copy, got ok=%v %+v", ok, result)
+	}
+	if linkChecks.Load() != checksAfterAnalyze {
+		t.Error("Expected links not to be rechecked")
+	}
+
+	result, ok = Revalidate(context.Background(), cached, AnalyzeOptions{}, true)
+	if !ok || len(result.AccessibleLinks) != 1 || linkChecks.Load() == checksAfterAnalyze {
+		t.Errorf("Expected links to be rechecked, got ok=%v accessible=%d", ok, len(result.AccessibleLinks))
+	}
+
+	etag = `"v2"`
+	if _, ok := Revalidate(context.Background(), cached, AnalyzeOptions{}, false); ok {
+		t.Error("Expected a changed page to need a full analysis")
+	}
+}
@@ -7,8 +7,90 @@ const (
 	// RequestTimeout defines the timeout for HTTP requests to fetch pages.
 	RequestTimeout = 10 * time.Second
 
+	// RenderTimeout defines the timeout for a Puppeteer render request.
+	RenderTimeout = 30 * time.Second
+
 	// LinkCheckTimeout defines the timeout for checking if a link is accessible.
 	LinkCheckTimeout = 5 * time.Second
+
+	// AnalysisTimeout is the default and maximum wall-time budget for a whole
+	// analysis, including link checks.
+	AnalysisTimeout = 2 * time.Minute
+)
+
+// DefaultRenderServer is the Puppeteer render server used when none is configured.
+const DefaultRenderServer = "http://localhost:3001"
+
+// StatusClientClosedRequest is the non-standard status used when the client
+// disconnects before the analysis finishes.
+const StatusClientClosedRequest = 499
+
+// LinkCheckConcurrency is the default number of links checked in parallel.
+const LinkCheckConcurrency = 10
+
+// MaxLinkCheckConcurrency caps the concurrency a caller may request.
+const MaxLinkCheckConcurrency = 50
+
+// Per-analysis budgets; callers may tighten but not exceed them
+const (
+	// MaxLinksChecked is the largest number of links checked for one page.
+	MaxLinksChecked = 1000
+
+	// MaxDOMNodes is the largest number of elements walked when parsing a page.
+	MaxDOMNodes = 100000
+)
+
+// Result cache defaults
+const (
+	// CacheMaxEntries is the number of results kept before the least
+	// recently used are evicted.
+	CacheMaxEntries = 1000
+
+	// CacheTTL is how long a cached result is served.
+	CacheTTL = 10 * time.Minute
+)
+
+// Link-status cache defaults
+const (
+	// LinkCacheSuccessTTL is how long an accessible link is not checked again.
+	LinkCacheSuccessTTL = 30 * time.Minute
+
+	// LinkCacheFailureTTL is how long an inaccessible link is not checked
+	// again; kept short so transient failures clear quickly.
+	LinkCacheFailureTTL = 2 * time.Minute
+
+	// LinkCacheMaxEntries bounds the number of remembered link outcomes.
+	LinkCacheMaxEntries = 50000
+)
+
+// Batch analysis limits
+const (
+	// MaxBatchURLs is the largest number of URLs accepted by one batch request.
+	MaxBatchURLs = 500
+
+	// BatchWorkers is the number of analyses a batch runs in parallel.
+	BatchWorkers = 4
+)
+
+// Default rate limits per client IP, as "N/unit,burst"
+const (
+	AnalyzeRateLimit = "10/m,5"
+	BatchRateLimit   = "2/m,2"
+)
+
+// DefaultUserAgent identifies the analyzer on outbound requests.
+const DefaultUserAgent = "web-analyzer/1.0 (+https://github.com/Thinura/go-web-analyzer)"
+
+// MaxRedirects is the number of HTTP redirects followed before a fetch gives up.
+const MaxRedirects = 10
+
+// Limits applied when reading a fetched page body
+const (
+	// MaxBodyBytes is the largest page body analyzed; anything beyond it is truncated.
+	MaxBodyBytes = 5 << 20
+
+	// MaxDecompressionRatio bounds decompressed/compressed size to stop gzip bombs.
+	MaxDecompressionRatio = 100
 )
 
 // DefaultHTMLVersion is used when no DOCTYPE is explicitly detected.
@@ -0,0 +1,139 @@
+package gate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+)
+
+// Thresholds are the quality rules a page must meet to pass the gate.
+type Thresholds struct {
+	MaxInaccessibleLinks  int      // -1 disables the check
+	RequireTitle          bool     // the page must have a non-empty <title>
+	RequireSingleH1       bool     // the page must have exactly one <h1>
+	ForbiddenHTMLVersions []string // case-insensitive substrings of Result.HTMLVersion, e.g. "XHTML"
+	FailOnPartial         bool     // an analysis cut short counts as a violation
+}
+
+// Check is the outcome of one rule.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// Summary is the gate verdict for one page, written as the JSON artifact.
+type Summary struct {
+	URL               string   `json:"url"`
+	FinalURL          string   `json:"finalUrl,omitempty"`
+	Passed            bool     `json:"passed"`
+	Checks            []Check  `json:"checks"`
+	InaccessibleLinks []string `json:"inaccessibleLinks,omitempty"`
+	DurationMs        int64    `json:"durationMs"`
+	Error             string   `json:"error,omitempty"` // why the page could not be analyzed
+}
+
+// Failed is the verdict for a page that could not be analyzed at all.
+func Failed(pageURL string, err error, duration time.Duration) Summary {
+	return Summary{URL: pageURL, Checks: []Check{}, DurationMs: duration.Milliseconds(), Error: err.Error()}
+}
+
+// Evaluate applies t to result. Every enabled rule is reported, passing or not.
+func Evaluate(result *analyzer.Result, t Thresholds) Summary {
+	s := Summary{
+		URL:        result.PageURL,
+		FinalURL:   result.FinalURL,
+		Passed:     true,
+		DurationMs: result.AnalysisDuration.Milliseconds(),
+	}
+	add := func(name string, passed bool, format string, args ...interface{}) {
+		s.Checks = append(s.Checks, Check{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)})
+		s.Passed = s.Passed && passed
+	}
+
+	if t.MaxInaccessibleLinks >= 0 {
+		n := len(result.InaccessibleLinks)
+		add("max-inaccessible-links", n <= t.MaxInaccessibleLinks, "%d inaccessible link(s), limit %d", n, t.MaxInaccessibleLinks)
+		for _, link := range result.InaccessibleLinks {
+			s.InaccessibleLinks = append(s.InaccessibleLinks, link.URL)
+		}
+	}
+
+	if t.RequireTitle {
+		title := strings.TrimSpace(result.Title)
+		if title == "" {
+			add("require-title", false, "page has no title")
+		} else {
+			add("require-title", true, "title %q", title)
+		}
+	}
+
+	if t.RequireSingleH1 {
+		h1 := 0
+		for _, h := range result.Headings {
+			if strings.EqualFold(h.Tag, "h1") {
+				h1++
+			}
+		}
+		add("require-single-h1", h1 == 1, "%d <h1> element(s)", h1)
+	}
+
+	if len(t.ForbiddenHTMLVersions) > 0 {
+		version := strings.ToLower(result.HTMLVersion)
+		forbidden := ""
+		for _, f := range t.ForbiddenHTMLVersions {
+			if f = strings.TrimSpace(f); f != "" && strings.Contains(version, strings.ToLower(f)) {
+				forbidden = f
+				break
+			}
+		}
+		if forbidden != "" {
+			add("forbidden-html-version", false, "%s matches forbidden %q", result.HTMLVersion, forbidden)
+		} else {
+			add("forbidden-html-version", true, "%s", result.HTMLVersion)
+		}
+	}
+
+	if t.FailOnPartial {
+		add("complete-analysis", !result.Partial, "partial=%t", result.Partial)
+	}
+
+	return s
+}
+
+// WriteText prints a human-readable pass/fail report of s.
+func WriteText(w io.Writer, s Summary) {
+	fmt.Fprintf(w, "Quality gate for %s\n", s.URL)
+	for _, c := range s.Checks {
+		mark := "PASS"
+		if !c.Passed {
+			mark = "FAIL"
+		}
+		fmt.Fprintf(w, "  [%s] %s: %s\n", mark, c.Name, c.Detail)
+	}
+	for _, link := range s.InaccessibleLinks {
+		fmt.Fprintf(w, "         inaccessible: %s\n", link)
+	}
+	if s.Passed {
+		fmt.Fprintln(w, "Result: PASSED")
+	} else {
+		fmt.Fprintln(w, "Result: FAILED")
+	}
+}
+
+// WriteJSON writes s to path for upload as a CI artifact.
+func WriteJSON(path string, s Summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write gate summary: %w", err)
+	}
+	return nil
+}
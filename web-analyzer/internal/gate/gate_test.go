@@ -0,0 +1,87 @@
+package gate
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"web-analyzer/internal/analyzer"
+)
+
+func goodPage() *analyzer.Result {
+	return &analyzer.Result{
+		PageURL:     "https://example.com/",
+		HTMLVersion: "HTML5",
+		Title:       "Example",
+		Headings:    []analyzer.Heading{{Tag: "h1", Title: "Welcome"}, {Tag: "h2", Title: "News"}},
+	}
+}
+
+func TestEvaluate_Passes(t *testing.T) {
+	s := Evaluate(goodPage(), Thresholds{
+		RequireTitle:          true,
+		RequireSingleH1:       true,
+		ForbiddenHTMLVersions: []string{"XHTML", "HTML 4.01"},
+	})
+	if !s.Passed || len(s.Checks) != 4 {
+		t.Errorf("Expected 4 passing checks, got %+v", s)
+	}
+}
+
+func TestEvaluate_Violations(t *testing.T) {
+	page := goodPage()
+	page.Title = "  "
+	page.HTMLVersion = "XHTML 1.0 Strict"
+	page.Headings = append(page.Headings, analyzer.Heading{Tag: "h1", Title: "Again"})
+	page.InaccessibleLinks = []analyzer.NamedLink{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}
+
+	s := Evaluate(page, Thresholds{
+		MaxInaccessibleLinks:  1,
+		RequireTitle:          true,
+		RequireSingleH1:       true,
+		ForbiddenHTMLVersions: []string{"xhtml"},
+	})
+	if s.Passed {
+		t.Fatal("Expected gate to fail")
+	}
+	for _, c := range s.Checks {
+		if c.Passed {
+			t.Errorf("Expected %s to fail: %s", c.Name, c.Detail)
+		}
+	}
+	if len(s.InaccessibleLinks) != 2 {
+		t.Errorf("Expected inaccessible links in summary, got %v", s.InaccessibleLinks)
+	}
+
+	var out bytes.Buffer
+	WriteText(&out, s)
+	if !strings.Contains(out.String(), "[FAIL] require-single-h1: 2 <h1> element(s)") || !strings.Contains(out.String(), "Result: FAILED") {
+		t.Errorf("Unexpected text summary:\n%s", out.String())
+	}
+}
+
+func TestEvaluate_DisabledLinkCheck(t *testing.T) {
+	page := goodPage()
+	page.InaccessibleLinks = []analyzer.NamedLink{{URL: "https://example.com/a"}}
+	if s := Evaluate(page, Thresholds{MaxInaccessibleLinks: -1}); !s.Passed || len(s.Checks) != 0 {
+		t.Errorf("Expected no checks to run, got %+v", s)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gate.json")
+	if err := WriteJSON(path, Evaluate(goodPage(), Thresholds{RequireTitle: true})); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil || !s.Passed || s.URL != "https://example.com/" {
+		t.Errorf("Unexpected summary %s (%v)", data, err)
+	}
+}
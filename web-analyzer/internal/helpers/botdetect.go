@@ -0,0 +1,181 @@
+package helpers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// BotSignals is what a detector gets to inspect about a fetched page.
+type BotSignals struct {
+	StatusCode int
+	Header     http.Header
+	Cookies    []*http.Cookie
+	Body       []byte
+
+	lowerBody string
+}
+
+// BodyContains reports whether the body contains substr, ignoring case.
+func (s *BotSignals) BodyContains(substr string) bool {
+	if s.lowerBody == "" && len(s.Body) > 0 {
+		s.lowerBody = strings.ToLower(string(s.Body))
+	}
+	return strings.Contains(s.lowerBody, strings.ToLower(substr))
+}
+
+// HasCookie reports whether a cookie with the given name prefix was set.
+func (s *BotSignals) HasCookie(prefix string) bool {
+	for _, c := range s.Cookies {
+		if strings.HasPrefix(c.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// BotDetection names the bot-protection vendor that blocked a fetch and why.
+type BotDetection struct {
+	Vendor string
+	Reason string
+}
+
+// BotDetector recognizes one vendor's challenge or block responses.
+type BotDetector interface {
+	Vendor() string
+	Detect(s *BotSignals) (reason string, ok bool)
+}
+
+type botDetectorFunc struct {
+	vendor string
+	detect func(s *BotSignals) (string, bool)
+}
+
+func (d botDetectorFunc) Vendor() string                      { return d.vendor }
+func (d botDetectorFunc) Detect(s *BotSignals) (string, bool) { return d.detect(s) }
+
+// NewBotDetector builds a BotDetector from a function.
+func NewBotDetector(vendor string, detect func(s *BotSignals) (string, bool)) BotDetector {
+	return botDetectorFunc{vendor: vendor, detect: detect}
+}
+
+var (
+	botDetectorsMu sync.RWMutex
+	botDetectors   = []BotDetector{
+		NewBotDetector("Cloudflare", detectCloudflare),
+		NewBotDetector("Akamai", detectAkamai),
+		NewBotDetector("DataDome", detectDataDome),
+		NewBotDetector("PerimeterX", detectPerimeterX),
+		NewBotDetector("Imperva", detectImperva),
+		NewBotDetector("reCAPTCHA", detectCaptcha("www.google.com/recaptcha/", "recaptcha/api.js")),
+		NewBotDetector("hCaptcha", detectCaptcha("hcaptcha.com/1/api.js", "js.hcaptcha.com")),
+	}
+)
+
+// RegisterBotDetector adds a detector that runs after the built-in ones.
+func RegisterBotDetector(d BotDetector) {
+	botDetectorsMu.Lock()
+	defer botDetectorsMu.Unlock()
+	botDetectors = append(botDetectors, d)
+}
+
+// DetectBotProtection runs the registered detectors in order and returns the
+// first match, or nil when the response looks like a normal page.
+func DetectBotProtection(s *BotSignals) *BotDetection {
+	botDetectorsMu.RLock()
+	defer botDetectorsMu.RUnlock()
+	for _, d := range botDetectors {
+		if reason, ok := d.Detect(s); ok {
+			return &BotDetection{Vendor: d.Vendor(), Reason: reason}
+		}
+	}
+	return nil
+}
+
+func isBlockStatus(code int) bool {
+	return code == http.StatusForbidden || code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+func detectCloudflare(s *BotSignals) (string, bool) {
+	switch {
+	case strings.EqualFold(s.Header.Get("Cf-Mitigated"), "challenge"):
+		return "cf-mitigated: challenge response header", true
+	case s.BodyContains("window._cf_chl_opt"):
+		return "challenge script (window._cf_chl_opt) in body", true
+	// Cloudflare injects /cdn-cgi/challenge-platform/scripts/jsd/main.js into
+	// normal pages as well, so the path alone is not a challenge
+	case (s.StatusCode == http.StatusForbidden || s.StatusCode == http.StatusServiceUnavailable) &&
+		s.BodyContains("/cdn-cgi/challenge-platform/"):
+		return "challenge platform script on block status", true
+	case isBlockStatus(s.StatusCode) && strings.EqualFold(s.Header.Get("Server"), "cloudflare") &&
+		s.BodyContains("attention required! | cloudflare"):
+		return "Cloudflare block page", true
+	}
+	return "", false
+}
+
+func detectAkamai(s *BotSignals) (string, bool) {
+	switch {
+	case s.BodyContains("/_sec/cp_challenge/") || s.BodyContains("sec-if-cpt-container"):
+		return "Bot Manager challenge in body", true
+	case s.StatusCode == http.StatusForbidden && strings.Contains(s.Header.Get("Server"), "AkamaiGHost"):
+		return "403 from AkamaiGHost edge server", true
+	}
+	return "", false
+}
+
+func detectDataDome(s *BotSignals) (string, bool) {
+	switch {
+	case s.BodyContains("captcha-delivery.com"):
+		return "captcha-delivery.com challenge in body", true
+	case isBlockStatus(s.StatusCode) && (s.Header.Get("X-DataDome") != "" || s.Header.Get("X-Dd-B") != ""):
+		return "block status with X-DataDome header", true
+	case isBlockStatus(s.StatusCode) && s.HasCookie("datadome"):
+		return "block status with datadome cookie", true
+	}
+	return "", false
+}
+
+func detectPerimeterX(s *BotSignals) (string, bool) {
+	switch {
+	case s.BodyContains("px-captcha") || s.BodyContains("_pxcaptcha"):
+		return "press-and-hold captcha in body", true
+	case isBlockStatus(s.StatusCode) && (s.HasCookie("_px") || s.BodyContains("window._pxappid")):
+		return "block status with PerimeterX sensor", true
+	}
+	return "", false
+}
+
+func detectImperva(s *BotSignals) (string, bool) {
+	switch {
+	case s.BodyContains("_incapsula_resource"):
+		return "Incapsula challenge resource in body", true
+	case s.BodyContains("incapsula incident id"):
+		return "Incapsula incident page", true
+	case isBlockStatus(s.StatusCode) && (s.Header.Get("X-Iinfo") != "" || s.HasCookie("incap_ses_")):
+		return "block status with Incapsula session", true
+	}
+	return "", false
+}
+
+var challengeTitle = regexp.MustCompile(`(?i)<title>[^<]*(verify you are (a )?human|are you a robot|security check|just a moment|access denied)[^<]*</title>`)
+
+// detectCaptcha only flags pages that embed a captcha widget and also look like
+// an interstitial, so ordinary sign-up forms with a captcha are not reported.
+func detectCaptcha(markers ...string) func(s *BotSignals) (string, bool) {
+	return func(s *BotSignals) (string, bool) {
+		for _, marker := range markers {
+			if !s.BodyContains(marker) {
+				continue
+			}
+			if isBlockStatus(s.StatusCode) {
+				return "captcha widget on a block status response", true
+			}
+			if challengeTitle.Match(s.Body) {
+				return "captcha widget on a challenge interstitial", true
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,115 @@
+package helpers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDetectBotProtection(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals BotSignals
+		vendor  string
+	}{
+		{
+			name:    "Cloudflare challenge header",
+			signals: BotSignals{StatusCode: 403, Header: http.Header{"Cf-Mitigated": {"challenge"}}},
+			vendor:  "Cloudflare",
+		},
+		{
+			name:    "Cloudflare challenge script",
+			signals: BotSignals{StatusCode: 200, Body: []byte(`<script>window._cf_chl_opt={}</script>`)},
+			vendor:  "Cloudflare",
+		},
+		{
+			name: "Cloudflare challenge platform on block status",
+			signals: BotSignals{StatusCode: 503, Body: []byte(
+				`<script src="/cdn-cgi/challenge-platform/h/g/orchestrate/chl_page/v1"></script>`)},
+			vendor: "Cloudflare",
+		},
+		{
+			name: "Cloudflare page with injected JS detection script is not a block",
+			signals: BotSignals{StatusCode: 200, Header: http.Header{"Server": {"cloudflare"}}, Body: []byte(
+				`<title>Home</title><script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script>`)},
+		},
+		{
+			name:    "Akamai edge denial",
+			signals: BotSignals{StatusCode: 403, Header: http.Header{"Server": {"AkamaiGHost"}}},
+			vendor:  "Akamai",
+		},
+		{
+			name:    "DataDome captcha",
+			signals: BotSignals{StatusCode: 403, Body: []byte(`<iframe src="https://geo.captcha-delivery.com/captcha/"></iframe>`)},
+			vendor:  "DataDome",
+		},
+		{
+			name:    "PerimeterX press and hold",
+			signals: BotSignals{StatusCode: 403, Body: []byte(`<div id="px-captcha"></div>`)},
+			vendor:  "PerimeterX",
+		},
+		{
+			name:    "Imperva incident",
+			signals: BotSignals{StatusCode: 200, Body: []byte(`Request unsuccessful. Incapsula incident ID: 123`)},
+			vendor:  "Imperva",
+		},
+		{
+			name: "reCAPTCHA interstitial",
+			signals: BotSignals{StatusCode: 200, Body: []byte(
+				`<title>Are you a robot?</title><script src="https://www.google.com/recaptcha/api.js"></script>`)},
+			vendor: "reCAPTCHA",
+		},
+		{
+			name:    "hCaptcha on block status",
+			signals: BotSignals{StatusCode: 429, Body: []byte(`<script src="https://js.hcaptcha.com/1/api.js"></script>`)},
+			vendor:  "hCaptcha",
+		},
+		{
+			name: "Sign-up form with captcha is not a block",
+			signals: BotSignals{StatusCode: 200, Body: []byte(
+				`<title>Create account</title><script src="https://www.google.com/recaptcha/api.js"></script>`)},
+		},
+		{
+			name:    "Page that mentions captcha is not a block",
+			signals: BotSignals{StatusCode: 200, Body: []byte(`<p>Our blog post about CAPTCHA usability</p>`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detection := DetectBotProtection(&tt.signals)
+			switch {
+			case tt.vendor == "" && detection != nil:
+				t.Errorf("Expected no detection, got %+v", detection)
+			case tt.vendor != "" && (detection == nil || detection.Vendor != tt.vendor):
+				t.Errorf("Expected vendor %s, got %+v", tt.vendor, detection)
+			case detection != nil && detection.Reason == "":
+				t.Errorf("Expected a reason for %s", detection.Vendor)
+			}
+		})
+	}
+}
+
+// resetBotDetectors restores the registered detectors when t ends.
+func resetBotDetectors(t *testing.T) {
+	botDetectorsMu.Lock()
+	original := append([]BotDetector(nil), botDetectors...)
+	botDetectorsMu.Unlock()
+	t.Cleanup(func() {
+		botDetectorsMu.Lock()
+		defer botDetectorsMu.Unlock()
+		botDetectors = original
+	})
+}
+
+func TestRegisterBotDetector(t *testing.T) {
+	resetBotDetectors(t)
+
+	RegisterBotDetector(NewBotDetector("Custom WAF", func(s *BotSignals) (string, bool) {
+		return "X-Custom-Block header", s.Header.Get("X-Custom-Block") != ""
+	}))
+
+	detection := DetectBotProtection(&BotSignals{StatusCode: 200, Header: http.Header{"X-Custom-Block": {"1"}}})
+	if detection == nil || detection.Vendor != "Custom WAF" {
+		t.Errorf("Expected custom detector to match, got %+v", detection)
+	}
+}
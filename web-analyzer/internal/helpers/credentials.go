@@ -0,0 +1,107 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Credentials authenticate requests to the analyzed site. They are only sent
+// to the page's own origin, never to third-party hosts.
+type Credentials struct {
+	Cookies      []*http.Cookie
+	CookieHeader string
+	BearerToken  string
+	Username     string
+	Password     string
+}
+
+// Authorization returns the Authorization header value, preferring a bearer
+// token over basic auth, or "" when neither is set.
+func (c *Credentials) Authorization() string {
+	switch {
+	case c == nil:
+		return ""
+	case c.BearerToken != "":
+		return "Bearer " + c.BearerToken
+	case c.Username != "" || c.Password != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+	}
+	return ""
+}
+
+// AllCookies merges Cookies with the pairs parsed from CookieHeader.
+func (c *Credentials) AllCookies() []*http.Cookie {
+	if c == nil {
+		return nil
+	}
+	cookies := append([]*http.Cookie(nil), c.Cookies...)
+	if c.CookieHeader != "" {
+		if parsed, err := http.ParseCookie(c.CookieHeader); err == nil {
+			cookies = append(cookies, parsed...)
+		}
+	}
+	return cookies
+}
+
+// AppliesTo reports whether credentials for pageURL may be sent to target:
+// same host and port, and no downgrade from HTTPS to HTTP.
+func (c *Credentials) AppliesTo(pageURL, target *url.URL) bool {
+	return c != nil && SameOrigin(pageURL, target)
+}
+
+// SameOrigin reports whether target is on pageURL's host and port without a
+// downgrade from HTTPS to HTTP, i.e. may receive what was meant for pageURL.
+func SameOrigin(pageURL, target *url.URL) bool {
+	if pageURL == nil || target == nil {
+		return false
+	}
+	if !strings.EqualFold(pageURL.Hostname(), target.Hostname()) {
+		return false
+	}
+	if pageURL.Scheme == "https" && target.Scheme != "https" {
+		return false
+	}
+	// Default ports on both sides also covers the usual http -> https upgrade
+	if pageURL.Port() == "" && target.Port() == "" {
+		return true
+	}
+	return effectivePort(pageURL) == effectivePort(target)
+}
+
+// Apply sets the credentials on req when req targets pageURL's origin.
+func (c *Credentials) Apply(req *http.Request, pageURL *url.URL) {
+	if !c.AppliesTo(pageURL, req.URL) {
+		return
+	}
+	if auth := c.Authorization(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	for _, cookie := range c.AllCookies() {
+		req.AddCookie(cookie)
+	}
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// StripURLCredentials removes user:password from rawURL, returning them as
+// basic auth credentials so they are used once and never echoed back.
+func StripURLCredentials(rawURL string) (string, *Credentials) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL, nil
+	}
+	password, _ := u.User.Password()
+	creds := &Credentials{Username: u.User.Username(), Password: password}
+	u.User = nil
+	return u.String(), creds
+}
@@ -0,0 +1,40 @@
+package helpers
+
+import "net/url"
+
+// Egress is how outbound requests for user-supplied URLs leave the process:
+// the SSRF guard that vets their destinations and the operator's default
+// proxy. A nil *Egress is the process-wide one, OutboundGuard and
+// DefaultProxy; an embedder gives each analyzer its own with NewEgress.
+type Egress struct {
+	guard      *SSRFGuard
+	proxy      *url.URL
+	transports transportPool
+}
+
+// NewEgress returns an egress vetted by guard, nil meaning the default
+// blocklist with nothing allowed, and routed through proxy, nil meaning
+// direct connections.
+func NewEgress(guard *SSRFGuard, proxy *url.URL) *Egress {
+	if guard == nil {
+		guard = NewSSRFGuard()
+	}
+	return &Egress{guard: guard, proxy: proxy}
+}
+
+// Guard returns the SSRF guard that vets e's connections.
+func (e *Egress) Guard() *SSRFGuard {
+	if e == nil {
+		return OutboundGuard
+	}
+	return e.guard
+}
+
+// DefaultProxy returns the proxy used when a request names none, or nil for
+// direct connections.
+func (e *Egress) DefaultProxy() *url.URL {
+	if e == nil {
+		return DefaultProxy()
+	}
+	return e.proxy
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"web-analyzer/internal/constants"
+)
+
+// FetchOptions carries per-analysis settings for page fetches and renders.
+// Zero values fall back to the defaults in constants.
+type FetchOptions struct {
+	UserAgent   string
+	Headers     map[string]string
+	Timeout     time.Duration
+	Credentials *Credentials
+	Proxy       string       // overrides Egress.DefaultProxy for this fetch
+	Egress      *Egress      // nil = OutboundGuard and DefaultProxy
+	Limits      *FetchLimits // nil = DefaultFetchLimits
+
+	RenderServer string // "" = $RENDER_SERVER_URL, else constants.DefaultRenderServer
+}
+
+func (o FetchOptions) userAgent() string {
+	if o.UserAgent != "" {
+		return o.UserAgent
+	}
+	return constants.DefaultUserAgent
+}
+
+// proxy returns the proxy to use, falling back to the egress default.
+func (o FetchOptions) proxy() (*url.URL, error) {
+	if o.Proxy == "" {
+		return o.Egress.DefaultProxy(), nil
+	}
+	return ParseProxyURL(o.Proxy)
+}
+
+// applyHeaders sets the user agent, any extra headers and the credentials on
+// req, which must target the analyzed page itself.
+func (o FetchOptions) applyHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", o.userAgent())
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	o.Credentials.Apply(req, req.URL)
+}
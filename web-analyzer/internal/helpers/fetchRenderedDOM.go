@@ -2,37 +2,115 @@ package helpers
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"os"
 	"time"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/logging"
+	"web-analyzer/pkg/errors"
 )
 
-var FetchRenderedDOM = func(url string) ([]byte, error) {
-	renderServer := os.Getenv("RENDER_SERVER_URL")
+// renderRequest is the JSON body accepted by the render server's /render endpoint.
+// The render server only attaches Authorization and Cookies to requests for
+// the page's own origin.
+type renderRequest struct {
+	URL           string            `json:"url"`
+	UserAgent     string            `json:"userAgent,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	TimeoutMs     int64             `json:"timeoutMs,omitempty"`
+	Authorization string            `json:"authorization,omitempty"`
+	Cookies       []renderCookie    `json:"cookies,omitempty"`
+	Proxy         string            `json:"proxy,omitempty"`
+}
+
+type renderCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// FetchRenderedDOM renders url through the Puppeteer render server.
+func FetchRenderedDOM(url string) ([]byte, error) {
+	return FetchRenderedDOMContext(context.Background(), url, FetchOptions{})
+}
+
+// FetchRenderedDOMContext is FetchRenderedDOM bound to ctx and opts; cancelling
+// ctx aborts the render request.
+var FetchRenderedDOMContext = func(ctx context.Context, url string, opts FetchOptions) ([]byte, error) {
+	// The render server fetches the page itself, so vet the target up front
+	if err := opts.Egress.Guard().CheckURL(ctx, url); err != nil {
+		return nil, fetchError(err, "render refused")
+	}
+
+	renderServer := opts.RenderServer
+	if renderServer == "" {
+		renderServer = os.Getenv("RENDER_SERVER_URL")
+	}
 	if renderServer == "" {
-		renderServer = "http://localhost:3001"
+		renderServer = constants.DefaultRenderServer
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = constants.RenderTimeout
 	}
 
-	body := fmt.Sprintf(`{"url": "%s"}`, url)
-	req, err := http.NewRequest("POST", renderServer+"/render", bytes.NewBuffer([]byte(body)))
+	proxy, err := opts.proxy()
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidOptions, err, "%v", err)
+	}
+
+	payload := renderRequest{
+		URL:           url,
+		UserAgent:     opts.UserAgent,
+		Headers:       opts.Headers,
+		TimeoutMs:     timeout.Milliseconds(),
+		Authorization: opts.Credentials.Authorization(),
+	}
+	if proxy != nil {
+		// Chrome connects to the proxy itself, so an untrusted one is vetted here
+		if err := opts.Egress.CheckProxy(ctx, proxy); err != nil {
+			return nil, fetchError(err, "render proxy refused")
+		}
+		payload.Proxy = proxy.String()
+	}
+	for _, c := range opts.Credentials.AllCookies() {
+		payload.Cookies = append(payload.Cookies, renderCookie{Name: c.Name, Value: c.Value})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", renderServer+"/render", bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	// Leave the render server time to report its own navigation timeout
+	client := &http.Client{Timeout: timeout + 5*time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, errors.New(errors.CodeRenderUnavailable, err, "render server unavailable: %v", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		// The render server refused a redirect or navigation to a blocked address
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.New(errors.CodeBlockedHost, nil, "render refused: %s", b)
+	}
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("render server error: %s", b)
+		return nil, errors.New(errors.CodeRenderUnavailable, nil, "render server error: %s", b)
 	}
 
-	return io.ReadAll(resp.Body)
+	// Read one byte past the limit so callers can tell the DOM was truncated
+	return io.ReadAll(io.LimitReader(resp.Body, opts.Limits.WithDefaults().MaxBodyBytes+1))
 }
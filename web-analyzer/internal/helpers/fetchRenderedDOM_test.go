@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"web-analyzer/pkg/errors"
+)
+
+func TestFetchRenderedDOM_RedirectToLoopbackIsBlocked(t *testing.T) {
+	// The render server stands in for Chromium following a public page's
+	// redirect to loopback, which its request interceptor refuses
+	render := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("blocked outbound connection to 127.0.0.1 (127.0.0.1): address is not publicly routable"))
+	}))
+	defer render.Close()
+
+	_, err := FetchRenderedDOMContext(context.Background(), "http://93.184.216.34/redirect", FetchOptions{RenderServer: render.URL})
+	if !stderrors.Is(err, errors.ErrBlockedHost) {
+		t.Fatalf("Expected blocked_host, got %v", err)
+	}
+}
+
+func TestFetchRenderedDOM_RefusesBlockedTarget(t *testing.T) {
+	_, err := FetchRenderedDOMContext(context.Background(), "http://169.254.169.254/latest/meta-data/", FetchOptions{RenderServer: "http://render.invalid"})
+	if !stderrors.Is(err, errors.ErrBlockedHost) {
+		t.Fatalf("Expected blocked_host before contacting the render server, got %v", err)
+	}
+}
+
+func TestFetchRenderedDOM_VetsPerRequestProxy(t *testing.T) {
+	var renders atomic.Int32
+	render := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renders.Add(1)
+		w.Write([]byte("<html></html>"))
+	}))
+	defer render.Close()
+	target := "http://93.184.216.34/"
+
+	_, err := FetchRenderedDOMContext(context.Background(), target, FetchOptions{RenderServer: render.URL, Proxy: "socks5://127.0.0.1:9050"})
+	if !stderrors.Is(err, errors.ErrBlockedHost) || renders.Load() != 0 {
+		t.Fatalf("Expected an internal per-request proxy to be refused before rendering, got %v after %d renders", err, renders.Load())
+	}
+
+	// The operator's own proxy is trusted even on a private address
+	proxy, _ := url.Parse("http://10.0.0.1:3128")
+	egress := NewEgress(nil, proxy)
+	if _, err := FetchRenderedDOMContext(context.Background(), target, FetchOptions{RenderServer: render.URL, Egress: egress}); err != nil {
+		t.Fatalf("Expected the default proxy to be accepted, got %v", err)
+	}
+	if _, err := FetchRenderedDOMContext(context.Background(), target, FetchOptions{RenderServer: render.URL, Egress: egress, Proxy: proxy.String()}); err != nil {
+		t.Fatalf("Expected the default proxy named per request to be accepted, got %v", err)
+	}
+	if renders.Load() != 2 {
+		t.Errorf("Expected 2 renders, got %d", renders.Load())
+	}
+}
+
+func TestFetchRenderedDOM_ReadsConfiguredLimit(t *testing.T) {
+	render := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>" + strings.Repeat("a", 1000) + "</html>"))
+	}))
+	defer render.Close()
+
+	dom, err := FetchRenderedDOMContext(context.Background(), "http://93.184.216.34/", FetchOptions{RenderServer: render.URL, Limits: &FetchLimits{MaxBodyBytes: 64}})
+	if err != nil {
+		t.Fatalf("FetchRenderedDOMContext failed: %v", err)
+	}
+	// One byte past the limit tells the caller the DOM was cut off
+	if len(dom) != 65 {
+		t.Errorf("Expected 65 bytes under a 64-byte limit, got %d", len(dom))
+	}
+}
@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
+	"net"
+	"syscall"
+
+	"web-analyzer/pkg/errors"
+)
+
+// fetchError classifies a failed request to the analyzed site, keeping err as
+// the cause. action prefixes the message, e.g. "failed to fetch".
+func fetchError(err error, action string) *errors.HTTPError {
+	var (
+		blocked     *BlockedAddressError
+		loop        *RedirectLoopError
+		tooMany     *TooManyRedirectsError
+		dnsErr      *net.DNSError
+		certErr     *tls.CertificateVerificationError
+		unknownAuth x509.UnknownAuthorityError
+		hostErr     x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		netErr      net.Error
+		opErr       *net.OpError
+	)
+	code := errors.CodeFetchFailed
+	switch {
+	case stderrors.As(err, &blocked):
+		code = errors.CodeBlockedHost
+	case stderrors.As(err, &loop), stderrors.As(err, &tooMany):
+		code = errors.CodeRedirectLoop
+	case stderrors.As(err, &dnsErr):
+		code = errors.CodeDNS
+	case stderrors.As(err, &certErr), stderrors.As(err, &unknownAuth), stderrors.As(err, &hostErr),
+		stderrors.As(err, &invalidErr), stderrors.As(err, &recordErr), stderrors.As(err, &alertErr):
+		code = errors.CodeTLS
+	case stderrors.As(err, &netErr) && netErr.Timeout():
+		code = errors.CodeTimeout
+	case stderrors.Is(err, syscall.ECONNREFUSED), stderrors.Is(err, syscall.ECONNRESET),
+		stderrors.Is(err, syscall.EHOSTUNREACH), stderrors.Is(err, syscall.ENETUNREACH),
+		stderrors.As(err, &opErr) && opErr.Op == "dial":
+		code = errors.CodeConnection
+	}
+	return errors.New(code, err, "%s: %v", action, err)
+}
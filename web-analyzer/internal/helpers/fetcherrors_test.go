@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"web-analyzer/pkg/errors"
+)
+
+func TestTryStandardFetch_TypedErrors(t *testing.T) {
+	allowLoopback(t)
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer slow.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		opts       FetchOptions
+		sentinel   error
+		wantStatus int
+	}{
+		{"untrusted certificate", tlsServer.URL, FetchOptions{}, errors.ErrTLS, http.StatusBadGateway},
+		{"connection refused", closedURL, FetchOptions{}, errors.ErrConnection, http.StatusBadGateway},
+		{"timeout", slow.URL, FetchOptions{Timeout: 50 * time.Millisecond}, errors.ErrTimeout, http.StatusGatewayTimeout},
+		{"invalid URL", "http://%zz", FetchOptions{}, errors.ErrInvalidURL, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := TryStandardFetchContext(context.Background(), tt.url, tt.opts)
+			if !stderrors.Is(err, tt.sentinel) {
+				t.Fatalf("Expected %s, got: %v", tt.sentinel.(*errors.HTTPError).Code, err)
+			}
+			var httpErr *errors.HTTPError
+			if !stderrors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got: %+v", tt.wantStatus, httpErr)
+			}
+			if tt.sentinel != errors.ErrInvalidURL && stderrors.Unwrap(err) == nil {
+				t.Errorf("Expected the cause to be wrapped: %v", err)
+			}
+		})
+	}
+}
+
+func TestFetchError_Classification(t *testing.T) {
+	tests := []struct {
+		err      error
+		sentinel error
+	}{
+		{&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}, errors.ErrDNS},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &BlockedAddressError{IP: netip.MustParseAddr("10.0.0.1")}}, errors.ErrBlockedHost},
+		{&RedirectLoopError{URL: "https://x.com/"}, errors.ErrRedirectLoop},
+		{&TooManyRedirectsError{Limit: 10}, errors.ErrRedirectLoop},
+		{fmt.Errorf("wrapped: %w", &net.OpError{Op: "dial", Net: "tcp", Err: stderrors.New("refused")}), errors.ErrConnection},
+		{stderrors.New("something else"), errors.ErrFetchFailed},
+	}
+	for _, tt := range tests {
+		err := fetchError(tt.err, "failed to fetch")
+		if !stderrors.Is(err, tt.sentinel) {
+			t.Errorf("fetchError(%v) = %s, want %s", tt.err, err.Code, tt.sentinel.(*errors.HTTPError).Code)
+		}
+		if !stderrors.Is(err, tt.err) {
+			t.Errorf("Expected %v to wrap its cause", err)
+		}
+	}
+	if stderrors.Is(fetchError(stderrors.New("x"), "failed"), errors.ErrDNS) {
+		t.Error("Expected codes not to match each other")
+	}
+}
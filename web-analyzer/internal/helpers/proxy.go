@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	defaultProxyMu sync.RWMutex
+	defaultProxy   *url.URL
+
+	// defaultTransports pools the transports of the process-wide egress
+	defaultTransports transportPool
+)
+
+// ParseProxyURL validates an http://, https:// or socks5:// proxy URL.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// SetDefaultProxy routes all outbound fetches and link checks through raw.
+// An empty string switches back to direct connections.
+func SetDefaultProxy(raw string) error {
+	var proxy *url.URL
+	if raw != "" {
+		var err error
+		if proxy, err = ParseProxyURL(raw); err != nil {
+			return err
+		}
+	}
+	defaultProxyMu.Lock()
+	defer defaultProxyMu.Unlock()
+	defaultProxy = proxy
+	return nil
+}
+
+// DefaultProxy returns the global proxy, or nil for direct connections.
+func DefaultProxy() *url.URL {
+	defaultProxyMu.RLock()
+	defer defaultProxyMu.RUnlock()
+	return defaultProxy
+}
+
+// maxCachedTransports bounds how many per-request proxies keep a pooled transport.
+const maxCachedTransports = 32
+
+// transportPool holds one pooled transport per proxy and trust level ("" = direct).
+type transportPool struct {
+	transports sync.Map
+	mu         sync.Mutex
+	count      int
+}
+
+// transportFor returns the shared transport for proxy so idle connections are
+// pooled across fetches and link checks.
+func (e *Egress) transportFor(proxy *url.URL) http.RoundTripper {
+	pool := &defaultTransports
+	if e != nil {
+		pool = &e.transports
+	}
+	trusted := e.trusted(proxy)
+	key := ""
+	if proxy != nil {
+		key = fmt.Sprintf("%t|%s", trusted, proxy)
+	}
+	if rt, ok := pool.transports.Load(key); ok {
+		return rt.(http.RoundTripper)
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.count >= maxCachedTransports {
+		return e.newTransport(proxy, trusted, false)
+	}
+	rt, loaded := pool.transports.LoadOrStore(key, e.newTransport(proxy, trusted, true))
+	if !loaded {
+		pool.count++
+	}
+	return rt.(http.RoundTripper)
+}
+
+// trusted reports whether proxy is e's default proxy, the operator's
+// configured egress, rather than one named by a request.
+func (e *Egress) trusted(proxy *url.URL) bool {
+	defaultProxy := e.DefaultProxy()
+	return proxy == nil || (defaultProxy != nil && proxy.String() == defaultProxy.String())
+}
+
+// CheckProxy vets a per-request proxy for a connection made by another
+// process, such as Chrome in the render server, the way newTransport dials it.
+func (e *Egress) CheckProxy(ctx context.Context, proxy *url.URL) error {
+	if e.trusted(proxy) {
+		return nil
+	}
+	return e.Guard().CheckHost(ctx, proxy.Hostname())
+}
+
+// newTransport builds a transport that vets every destination. A trusted
+// proxy is the operator's configured egress and may sit on a private address;
+// per-request proxies are dialed through the guard like any other host.
+func (e *Egress) newTransport(proxy *url.URL, trusted, keepAlive bool) http.RoundTripper {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return e.Guard().DialContext(ctx, network, addr)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     !keepAlive,
+	}
+	if proxy == nil {
+		return transport
+	}
+
+	transport.Proxy = http.ProxyURL(proxy)
+	if trusted {
+		transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	return &proxyGuard{egress: e, next: transport}
+}
+
+// proxyGuard resolves and vets each target, redirects included, before it is
+// handed to the proxy, since the dialer never sees the target's address.
+type proxyGuard struct {
+	egress *Egress
+	next   http.RoundTripper
+}
+
+func (p *proxyGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := p.egress.Guard().CheckURL(req.Context(), req.URL.String()); err != nil {
+		return nil, err
+	}
+	return p.next.RoundTrip(req)
+}
@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Validators are the cache validators a page was served with.
+type Validators struct {
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+}
+
+// validatorsFrom returns h's validators, or nil when it has none.
+func validatorsFrom(h http.Header) *Validators {
+	v := &Validators{ETag: h.Get("ETag"), LastModified: h.Get("Last-Modified")}
+	if v.ETag == "" && v.LastModified == "" {
+		return nil
+	}
+	return v
+}
+
+// errModified is returned by the redirect policy so a changed page is not
+// downloaded a second time.
+var errModified = stderrors.New("page moved since it was cached")
+
+// NotModified issues a conditional GET for url with If-None-Match and
+// If-Modified-Since and reports whether the server answered 304. Any other
+// answer, including a redirect, means the page must be fetched again.
+func NotModified(ctx context.Context, url string, v *Validators, opts FetchOptions) (bool, error) {
+	if v == nil {
+		return false, nil
+	}
+	timeout := opts.Limits.WithDefaults().ReadTimeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	proxy, err := opts.proxy()
+	if err != nil {
+		return false, err
+	}
+	client := opts.Egress.Client(timeout, proxy)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return errModified }
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	opts.applyHeaders(req)
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if stderrors.Is(err, errModified) {
+			return false, nil
+		}
+		return false, fmt.Errorf("conditional request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	return resp.StatusCode == http.StatusNotModified, nil
+}
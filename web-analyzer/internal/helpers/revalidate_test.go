@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotModified(t *testing.T) {
+	allowLoopback(t)
+
+	etag := `"v1"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer ts.Close()
+
+	fetched, err := TryStandardFetch(ts.URL)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Validators == nil || fetched.Validators.ETag != etag || fetched.Validators.LastModified == "" {
+		t.Fatalf("Expected validators to be recorded, got %+v", fetched.Validators)
+	}
+
+	if ok, err := NotModified(context.Background(), ts.URL, fetched.Validators, FetchOptions{}); err != nil || !ok {
+		t.Errorf("Expected 304 for unchanged page, got %v %v", ok, err)
+	}
+
+	etag = `"v2"`
+	if ok, err := NotModified(context.Background(), ts.URL, fetched.Validators, FetchOptions{}); err != nil || ok {
+		t.Errorf("Expected changed page to need a refetch, got %v %v", ok, err)
+	}
+	if ok, err := NotModified(context.Background(), ts.URL+"/moved", &Validators{ETag: etag}, FetchOptions{}); err != nil || ok {
+		t.Errorf("Expected a redirect to need a refetch, got %v %v", ok, err)
+	}
+	if ok, _ := NotModified(context.Background(), ts.URL, nil, FetchOptions{}); ok {
+		t.Error("Expected no validators to need a refetch")
+	}
+}
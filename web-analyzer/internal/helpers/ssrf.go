@@ -0,0 +1,214 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"web-analyzer/internal/constants"
+)
+
+// BlockedAddressError is returned when an outbound connection would reach a
+// loopback, private, link-local or otherwise reserved address.
+type BlockedAddressError struct {
+	Host string
+	IP   netip.Addr
+}
+
+func (e *BlockedAddressError) Error() string {
+	if e.Host != "" && e.Host != e.IP.String() {
+		return fmt.Sprintf("blocked outbound connection to %s (%s): address is not publicly routable", e.Host, e.IP)
+	}
+	return fmt.Sprintf("blocked outbound connection to %s: address is not publicly routable", e.IP)
+}
+
+// Reserved ranges not covered by the netip.Addr classification helpers.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),          // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),      // carrier-grade NAT
+	netip.MustParsePrefix("100.100.100.200/32"), // Alibaba Cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),       // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),      // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),        // reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),       // NAT64, can embed private IPv4
+}
+
+// SSRFGuard vets outbound connections after DNS resolution so that user
+// supplied URLs cannot be used to reach internal services. Hosts and CIDRs on
+// the allowlist are trusted and bypass the check.
+type SSRFGuard struct {
+	mu           sync.RWMutex
+	allowedHosts map[string]bool
+	allowedNets  []netip.Prefix
+}
+
+// OutboundGuard protects page fetches, link checks and render targets.
+// Its allowlist is seeded from the comma-separated SSRF_ALLOWLIST variable.
+var OutboundGuard = NewSSRFGuard(strings.Split(os.Getenv("SSRF_ALLOWLIST"), ",")...)
+
+// NewSSRFGuard creates a guard that trusts the given hostnames, IPs or CIDRs.
+func NewSSRFGuard(allow ...string) *SSRFGuard {
+	g := &SSRFGuard{allowedHosts: make(map[string]bool)}
+	for _, entry := range allow {
+		g.Allow(entry)
+	}
+	return g
+}
+
+// Allow adds a hostname, IP address or CIDR block to the allowlist.
+func (g *SSRFGuard) Allow(entry string) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if entry == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		g.allowedNets = append(g.allowedNets, prefix.Masked())
+		return
+	}
+	if ip, err := netip.ParseAddr(entry); err == nil {
+		g.allowedNets = append(g.allowedNets, netip.PrefixFrom(ip, ip.BitLen()))
+		return
+	}
+	g.allowedHosts[entry] = true
+}
+
+func (g *SSRFGuard) hostAllowed(host string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.allowedHosts[strings.ToLower(strings.TrimSuffix(host, "."))]
+}
+
+func (g *SSRFGuard) checkIP(host string, ip netip.Addr) error {
+	ip = ip.Unmap()
+	g.mu.RLock()
+	for _, prefix := range g.allowedNets {
+		if prefix.Contains(ip) {
+			g.mu.RUnlock()
+			return nil
+		}
+	}
+	g.mu.RUnlock()
+
+	if isBlockedIP(ip) {
+		return &BlockedAddressError{Host: host, IP: ip}
+	}
+	return nil
+}
+
+func isBlockedIP(ip netip.Addr) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialContext dials addr, refusing connections whose resolved IP is blocked.
+// The check runs in the dialer's Control hook, i.e. on the address actually
+// being connected to, so it also covers redirects and DNS rebinding.
+func (g *SSRFGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !g.hostAllowed(host) {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			ipStr, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(ipStr)
+			if err != nil {
+				return err
+			}
+			return g.checkIP(host, ip)
+		}
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// CheckURL resolves the URL's host and fails if any of its addresses are
+// blocked. It is used where the connection is made by another process, such
+// as the Puppeteer render server, and the dialer hook cannot be applied.
+func (g *SSRFGuard) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	return g.CheckHost(ctx, u.Hostname())
+}
+
+// CheckHost resolves host and fails if any of its addresses are blocked.
+func (g *SSRFGuard) CheckHost(ctx context.Context, host string) error {
+	if g.hostAllowed(host) {
+		return nil
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return g.checkIP(host, ip)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range addrs {
+		if err := g.checkIP(host, ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient returns a client for user-supplied URLs whose connections are
+// vetted by OutboundGuard and routed through the default proxy, if any.
+// Redirects to non-HTTP schemes are refused and credentials are dropped when
+// a redirect leaves the original host.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return NewProxiedHTTPClient(timeout, DefaultProxy())
+}
+
+// NewProxiedHTTPClient is NewHTTPClient routed through proxy; nil connects directly.
+func NewProxiedHTTPClient(timeout time.Duration, proxy *url.URL) *http.Client {
+	return (*Egress)(nil).Client(timeout, proxy)
+}
+
+// Client is NewProxiedHTTPClient with connections vetted by e's guard.
+func (e *Egress) Client(timeout time.Duration, proxy *url.URL) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: e.transportFor(proxy),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("refusing redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			if len(via) >= constants.MaxRedirects {
+				return &TooManyRedirectsError{Limit: constants.MaxRedirects}
+			}
+			// Credentials never follow a redirect off the original host
+			if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+				req.Header.Del("Authorization")
+				req.Header.Del("Cookie")
+			}
+			return nil
+		},
+	}
+}
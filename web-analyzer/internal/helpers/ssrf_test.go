@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+	for _, tt := range tests {
+		ip := netip.MustParseAddr(tt.ip).Unmap()
+		if got := isBlockedIP(ip); got != tt.blocked {
+			t.Errorf("isBlockedIP(%s) = %v, expected %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestSSRFGuard_CheckURL(t *testing.T) {
+	guard := NewSSRFGuard("10.0.0.0/24", "render.internal")
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://127.0.0.1:8080/", true},
+		{"http://[::1]/", true},
+		{"http://10.0.0.5/", false},
+		{"http://10.0.1.5/", true},
+		{"http://render.internal/", false},
+		{"http://93.184.216.34/", false},
+	}
+	for _, tt := range tests {
+		err := guard.CheckURL(context.Background(), tt.url)
+		var blocked *BlockedAddressError
+		if got := stderrors.As(err, &blocked); got != tt.blocked {
+			t.Errorf("CheckURL(%s): blocked=%v, expected %v (err: %v)", tt.url, got, tt.blocked, err)
+		}
+	}
+
+	if err := guard.CheckURL(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("Expected non-HTTP scheme to be rejected")
+	}
+}
+
+func TestNewHTTPClient_BlocksLoopbackAndRedirects(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+
+	client := NewHTTPClient(2 * time.Second)
+	_, err := client.Get(internal.URL)
+	var blocked *BlockedAddressError
+	if !stderrors.As(err, &blocked) {
+		t.Fatalf("Expected blocked loopback fetch, got: %v", err)
+	}
+
+	// A trusted host may redirect, but the hop to a blocked address must still fail
+	original := OutboundGuard
+	OutboundGuard = NewSSRFGuard("localhost")
+	defer func() { OutboundGuard = original }()
+
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	_, err = client.Get(strings.Replace(redirector.URL, "127.0.0.1", "localhost", 1))
+	if !stderrors.As(err, &blocked) {
+		t.Errorf("Expected redirect into loopback to be blocked, got: %v", err)
+	}
+}
@@ -1,12 +1,167 @@
 package helpers
 
 import (
+	"net"
+	"net/url"
+	"sort"
 	"strings"
 )
 
-// NormalizeURL trims whitespace and lowercases a URL
-func NormalizeURL(url string) string {
-	return strings.TrimSpace(strings.ToLower(url))
+// NormalizeURL applies RFC 3986 syntax-based normalization so equivalent URLs
+// compare equal: scheme and host are lowercased, default ports and fragments
+// are dropped, an empty path becomes "/", dot segments are removed,
+// percent-encoding is canonicalized and query parameters are sorted. Path and
+// query case is preserved. Unparseable input is returned trimmed.
+func NormalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Opaque != "" || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := u.Hostname(), u.Port()
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	p := removeDotSegments(normalizePercentEncoding(u.EscapedPath()))
+	if p == "" {
+		p = "/"
+	}
+	u.Path, u.RawPath = "", ""
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		u.Path, u.RawPath = unescaped, p
+	}
+
+	u.RawQuery = sortQuery(u.RawQuery)
+	u.ForceQuery = false
+	return u.String()
+}
+
+// removeDotSegments removes "." and ".." segments from an escaped absolute
+// path (RFC 3986 section 5.2.4). It works on the string itself, so empty
+// segments survive and "//a.com/x" is not read as an authority.
+func removeDotSegments(p string) string {
+	segments := strings.Split(p, "/")
+	out := make([]string, 0, len(segments))
+	for i, seg := range segments {
+		if seg != "." && seg != ".." {
+			out = append(out, seg)
+			continue
+		}
+		if seg == ".." && len(out) > 1 {
+			out = out[:len(out)-1]
+		}
+		// A trailing dot segment leaves the path ending in "/"
+		if i == len(segments)-1 {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+// trackingParams are query parameters that identify a campaign or click and
+// never change page content.
+var trackingParams = map[string]bool{
+	"gclid": true, "dclid": true, "fbclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "_ga": true, "_gl": true,
+}
+
+// StripTrackingParams removes utm_* and other click-tracking parameters.
+func StripTrackingParams(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	parts := strings.Split(u.RawQuery, "&")
+	kept := parts[:0]
+	for _, part := range parts {
+		name, _, _ := strings.Cut(part, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "utm_") || trackingParams[name] {
+			continue
+		}
+		kept = append(kept, part)
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
+
+// sortQuery orders query parameters by name, keeping the relative order of
+// repeated names, and canonicalizes their percent-encoding.
+func sortQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var parts []string
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part != "" {
+			parts = append(parts, normalizePercentEncoding(part))
+		}
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		ni, _, _ := strings.Cut(parts[i], "=")
+		nj, _, _ := strings.Cut(parts[j], "=")
+		return ni < nj
+	})
+	return strings.Join(parts, "&")
+}
+
+// normalizePercentEncoding uppercases percent-encoded octets and decodes the
+// ones that stand for unreserved characters (RFC 3986 section 6.2.2.2).
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
 }
 
 // IsEmpty checks if a string is empty after trimming spaces
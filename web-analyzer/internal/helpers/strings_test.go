@@ -0,0 +1,51 @@
+package helpers
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://x.com", "https://x.com/"},
+		{"https://x.com/", "https://x.com/"},
+		{"HTTPS://X.COM", "https://x.com/"},
+		{"  https://x.com:443/  ", "https://x.com/"},
+		{"http://x.com:80/a", "http://x.com/a"},
+		{"http://x.com:8080/a", "http://x.com:8080/a"},
+		{"https://x.com/Case/Path", "https://x.com/Case/Path"},
+		{"https://x.com/a/./b/../c", "https://x.com/a/c"},
+		{"https://x.com/a/b/..", "https://x.com/a/"},
+		{"https://x.com/../a", "https://x.com/a"},
+		{"https://a.com//evil.com/x", "https://a.com//evil.com/x"},
+		{"https://a.com//evil.com/./x/../y", "https://a.com//evil.com/y"},
+		{"https://a.com/x//y", "https://a.com/x//y"},
+		{"https://x.com/a#section", "https://x.com/a"},
+		{"https://x.com/?b=2&a=1&b=1", "https://x.com/?a=1&b=2&b=1"},
+		{"https://x.com/%7euser/%2f%c3%a9", "https://x.com/~user/%2F%C3%A9"},
+		{"https://x.com/?q=A%2fB", "https://x.com/?q=A%2FB"},
+		{"http://[::1]:80/", "http://[::1]/"},
+		{"https://x.com./", "https://x.com/"},
+		{"not a url", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.in); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripTrackingParams(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://x.com/?utm_source=a&id=1&UTM_Medium=b", "https://x.com/?id=1"},
+		{"https://x.com/?gclid=1&fbclid=2", "https://x.com/"},
+		{"https://x.com/?q=utm_source", "https://x.com/?q=utm_source"},
+		{"https://x.com/", "https://x.com/"},
+	}
+	for _, tt := range tests {
+		if got := StripTrackingParams(tt.in); got != tt.want {
+			t.Errorf("StripTrackingParams(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
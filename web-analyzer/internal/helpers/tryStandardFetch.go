@@ -1,36 +1,303 @@
 package helpers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
+	"web-analyzer/internal/constants"
 	"web-analyzer/pkg/errors"
 )
 
-func TryStandardFetch(url string) ([]byte, bool, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
+// FetchLimits bounds how much a single page fetch or render may consume.
+type FetchLimits struct {
+	MaxBodyBytes          int64         // fetched or rendered HTML beyond this is truncated
+	ReadTimeout           time.Duration // used when FetchOptions.Timeout is unset
+	MaxDecompressionRatio float64
+}
+
+// DefaultFetchLimits applies when FetchOptions.Limits is nil. The server sets
+// it from FETCH_MAX_BODY_BYTES and FETCH_READ_TIMEOUT.
+var DefaultFetchLimits = FetchLimits{
+	MaxBodyBytes:          constants.MaxBodyBytes,
+	ReadTimeout:           constants.RequestTimeout,
+	MaxDecompressionRatio: constants.MaxDecompressionRatio,
+}
+
+// WithDefaults returns l with unset fields taken from DefaultFetchLimits; a
+// nil l is DefaultFetchLimits.
+func (l *FetchLimits) WithDefaults() FetchLimits {
+	limits := DefaultFetchLimits
+	if l == nil {
+		return limits
+	}
+	if l.MaxBodyBytes > 0 {
+		limits.MaxBodyBytes = l.MaxBodyBytes
+	}
+	if l.ReadTimeout > 0 {
+		limits.ReadTimeout = l.ReadTimeout
+	}
+	if l.MaxDecompressionRatio > 0 {
+		limits.MaxDecompressionRatio = l.MaxDecompressionRatio
+	}
+	return limits
+}
+
+// RedirectHop is a single HTTP redirect followed while fetching a page.
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+	Permanent  bool
+}
+
+// FetchResult is the outcome of a standard HTTP page fetch.
+type FetchResult struct {
+	Body        []byte
+	StatusCode  int
+	ContentType string
+	FinalURL    string
+	Redirects   []RedirectHop
+	Truncated   bool
+	Validators  *Validators // ETag and Last-Modified of a 200 response
+	// BotProtection is set when the response is a bot-protection challenge or block
+	BotProtection *BotDetection
+}
+
+// analyzableTypes are the media types that can be parsed as a page. text/plain
+// is included because servers often mislabel HTML fragments.
+var analyzableTypes = map[string]bool{
+	"text/html":             true,
+	"application/xhtml+xml": true,
+	"text/plain":            true,
+}
+
+// AnalyzableContent returns the media type of a fetched page, sniffed from
+// body when contentType is empty, or an unsupported_content error when the
+// page cannot be parsed as HTML.
+func AnalyzableContent(contentType string, body []byte) (string, error) {
+	mediaType := contentType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+	}
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+	mediaType = strings.ToLower(mediaType)
+	if !analyzableTypes[mediaType] {
+		return "", errors.New(errors.CodeUnsupportedContent, nil, "unsupported content type %q: only HTML pages can be analyzed", mediaType)
+	}
+	return mediaType, nil
+}
+
+// ratioCheckFloor is the decompressed size below which the ratio is not enforced,
+// since small, repetitive pages legitimately compress very well.
+const ratioCheckFloor = 1 << 20
+
+// RedirectLoopError reports a redirect chain that revisits a URL.
+type RedirectLoopError struct {
+	URL  string
+	Hops []RedirectHop
+}
+
+func (e *RedirectLoopError) Error() string {
+	return fmt.Sprintf("redirect loop detected at %s after %d hops", e.URL, len(e.Hops))
+}
+
+// TooManyRedirectsError reports a redirect chain longer than the limit.
+type TooManyRedirectsError struct {
+	Limit int
+}
+
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("stopped after %d redirects", e.Limit)
+}
+
+// ContextError describes why ctx ended as an HTTPError for the named phase.
+func ContextError(ctx context.Context, phase string) error {
+	if stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.New(errors.CodeTimeout, ctx.Err(), "%s: analysis deadline exceeded", phase)
+	}
+	return errors.New(errors.CodeClientClosedRequest, ctx.Err(), "%s: request cancelled", phase)
+}
+
+var errDecompressionBomb = stderrors.New("decompressed body exceeds the allowed compression ratio")
+
+func TryStandardFetch(url string) (*FetchResult, error) {
+	return TryStandardFetchContext(context.Background(), url, FetchOptions{})
+}
+
+// TryStandardFetchContext is TryStandardFetch bound to ctx and opts; cancelling
+// ctx aborts the request and the body read.
+func TryStandardFetchContext(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
+	start := time.Now()
+	result, err := fetchPage(ctx, url, opts)
+	if err != nil {
+		slog.InfoContext(ctx, "page fetch failed", "url", url, "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+	slog.DebugContext(ctx, "page fetched",
+		"url", url,
+		"final_url", result.FinalURL,
+		"status", result.StatusCode,
+		"redirects", len(result.Redirects),
+		"bytes", len(result.Body),
+		"truncated", result.Truncated,
+		"duration", time.Since(start),
+	)
+	if result.BotProtection != nil {
+		slog.InfoContext(ctx, "bot protection detected", "url", url, "vendor", result.BotProtection.Vendor, "reason", result.BotProtection.Reason)
+	}
+	return result, nil
+}
+
+func fetchPage(ctx context.Context, url string, opts FetchOptions) (*FetchResult, error) {
+	limits := opts.Limits.WithDefaults()
+	timeout := limits.ReadTimeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	proxy, err := opts.proxy()
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidOptions, err, "%v", err)
+	}
+	client := opts.Egress.Client(timeout, proxy)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, false, &errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("failed to fetch: %v", err)}
+		return nil, errors.New(errors.CodeInvalidURL, err, "failed to fetch: %v", err)
+	}
+	opts.applyHeaders(req)
+	// Ask for gzip explicitly so the transport does not decompress transparently
+	// and the compression ratio can be watched while reading.
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// Record every hop and stop on loops before handing off to the default policy
+	var hops []RedirectHop
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		prev := next.Response
+		hops = append(hops, RedirectHop{
+			URL:        prev.Request.URL.String(),
+			StatusCode: prev.StatusCode,
+			Location:   prev.Header.Get("Location"),
+			Permanent:  prev.StatusCode == http.StatusMovedPermanently || prev.StatusCode == http.StatusPermanentRedirect,
+		})
+		for _, v := range via {
+			if v.URL.String() == next.URL.String() {
+				return &RedirectLoopError{URL: next.URL.String(), Hops: hops}
+			}
+		}
+		return checkRedirect(next, via)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ContextError(ctx, "fetch")
+		}
+		return nil, fetchError(err, "failed to fetch")
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
-		// Likely a redirect to bot-check or login
-		return nil, true, nil
+	result := &FetchResult{
+		StatusCode: resp.StatusCode,
+		FinalURL:   resp.Request.URL.String(),
+		Redirects:  hops,
+	}
+	if resp.StatusCode == http.StatusOK {
+		result.Validators = validatorsFrom(resp.Header)
+	}
+
+	// Refuse a declared non-HTML type before downloading the body
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		if result.ContentType, err = AnalyzableContent(contentType, nil); err != nil {
+			return nil, err
+		}
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, truncated, err := readLimitedBody(resp, limits)
 	if err != nil {
-		return nil, false, &errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("failed to read body: %v", err)}
+		if ctx.Err() != nil {
+			return nil, ContextError(ctx, "fetch")
+		}
+		return nil, fetchError(err, "failed to read body")
 	}
+	result.Body = data
+	result.Truncated = truncated
 
-	// Check for common bot-block HTML signs
-	if strings.Contains(strings.ToLower(string(data)), "captcha") || strings.Contains(string(data), "window._cf_chl_opt") {
-		return data, true, nil
+	if result.ContentType == "" {
+		if result.ContentType, err = AnalyzableContent("", data); err != nil {
+			return nil, err
+		}
 	}
 
+	result.BotProtection = DetectBotProtection(&BotSignals{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Cookies:    resp.Cookies(),
+		Body:       data,
+	})
+
+	return result, nil
+}
+
+// readLimitedBody reads at most limits.MaxBodyBytes of the (possibly gzipped)
+// body and reports whether anything was cut off.
+func readLimitedBody(resp *http.Response, limits FetchLimits) ([]byte, bool, error) {
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		compressed := &countingReader{r: resp.Body}
+		gz, err := gzip.NewReader(compressed)
+		if err != nil {
+			return nil, false, err
+		}
+		defer gz.Close()
+		body = &ratioGuard{r: gz, compressed: compressed, maxRatio: limits.MaxDecompressionRatio}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, limits.MaxBodyBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limits.MaxBodyBytes {
+		return bytes.Clone(data[:limits.MaxBodyBytes]), true, nil
+	}
 	return data, false, nil
 }
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ratioGuard aborts decompression once the output outgrows the compressed input
+// by more than maxRatio.
+type ratioGuard struct {
+	r          io.Reader
+	compressed *countingReader
+	maxRatio   float64
+	n          int64
+}
+
+func (g *ratioGuard) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	g.n += int64(n)
+	if g.maxRatio > 0 && g.n > ratioCheckFloor && float64(g.n) > g.maxRatio*float64(g.compressed.n) {
+		return n, errDecompressionBomb
+	}
+	return n, err
+}
@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/constants"
+	"web-analyzer/pkg/errors"
+)
+
+// allowLoopback lets the test reach httptest servers through the SSRF guard.
+func allowLoopback(t *testing.T) {
+	t.Helper()
+	original := OutboundGuard
+	OutboundGuard = NewSSRFGuard("127.0.0.1")
+	t.Cleanup(func() { OutboundGuard = original })
+}
+
+func TestTryStandardFetch_RejectsNonHTML(t *testing.T) {
+	allowLoopback(t)
+
+	for _, contentType := range []string{"application/pdf", "image/png", "application/json"} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.Write([]byte("{}"))
+		}))
+
+		_, err := TryStandardFetch(ts.URL)
+		httpErr, ok := err.(*errors.HTTPError)
+		if !ok || httpErr.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(httpErr.Message, contentType) {
+			t.Errorf("Expected 422 for %s, got: %v", contentType, err)
+		}
+		ts.Close()
+	}
+}
+
+func TestTryStandardFetch_TruncatesLargeBody(t *testing.T) {
+	allowLoopback(t)
+	original := DefaultFetchLimits
+	DefaultFetchLimits.MaxBodyBytes = 64
+	defer func() { DefaultFetchLimits = original }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>" + strings.Repeat("a", 1000) + "</body></html>"))
+	}))
+	defer ts.Close()
+
+	result, err := TryStandardFetch(ts.URL)
+	if err != nil {
+		t.Fatalf("TryStandardFetch failed: %v", err)
+	}
+	if !result.Truncated || len(result.Body) != 64 {
+		t.Errorf("Expected truncated 64-byte body, got truncated=%v len=%d", result.Truncated, len(result.Body))
+	}
+}
+
+func TestTryStandardFetch_PerFetchLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>" + strings.Repeat("a", 1000) + "</body></html>"))
+	}))
+	defer ts.Close()
+	opts := FetchOptions{
+		Limits: &FetchLimits{MaxBodyBytes: 64, ReadTimeout: 50 * time.Millisecond},
+		Egress: NewEgress(NewSSRFGuard("127.0.0.1"), nil),
+	}
+
+	result, err := TryStandardFetchContext(context.Background(), ts.URL, opts)
+	if err != nil {
+		t.Fatalf("TryStandardFetchContext failed: %v", err)
+	}
+	if !result.Truncated || len(result.Body) != 64 {
+		t.Errorf("Expected truncated 64-byte body, got truncated=%v len=%d", result.Truncated, len(result.Body))
+	}
+	if _, err := TryStandardFetchContext(context.Background(), ts.URL+"/slow", opts); !stderrors.Is(err, errors.ErrTimeout) {
+		t.Errorf("Expected the configured read timeout to apply, got %v", err)
+	}
+	if DefaultFetchLimits.MaxBodyBytes != constants.MaxBodyBytes {
+		t.Errorf("Expected per-fetch limits to leave the defaults alone, got %d", DefaultFetchLimits.MaxBodyBytes)
+	}
+}
+
+func TestTryStandardFetch_Gzip(t *testing.T) {
+	allowLoopback(t)
+
+	gzipped := func(payload string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(payload))
+		gz.Close()
+		return buf.Bytes()
+	}
+
+	page := "<html><title>Compressed</title></html>"
+	bomb := "<html>" + strings.Repeat(" ", 8<<20)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.URL.Path == "/bomb" {
+			w.Write(gzipped(bomb))
+			return
+		}
+		w.Write(gzipped(page))
+	}))
+	defer ts.Close()
+
+	result, err := TryStandardFetch(ts.URL)
+	if err != nil {
+		t.Fatalf("TryStandardFetch failed: %v", err)
+	}
+	if string(result.Body) != page {
+		t.Errorf("Expected decompressed page, got %q", result.Body)
+	}
+
+	_, err = TryStandardFetch(ts.URL + "/bomb")
+	if err == nil || !strings.Contains(err.Error(), "compression ratio") {
+		t.Errorf("Expected decompression ratio error, got: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetup_JSONWithRequestID(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	if err := Setup("debug", "json", &buf); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "abc-123")
+	slog.DebugContext(ctx, "page fetched", "url", "https://example.com")
+	slog.With("component", "test").InfoContext(ctx, "derived logger")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected JSON log line, got %q", line)
+		}
+		if entry["request_id"] != "abc-123" {
+			t.Errorf("Expected request_id on %q", line)
+		}
+	}
+}
+
+func TestSetup_LevelFilters(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	if err := Setup("warn", "", &buf); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	slog.Info("hidden")
+	slog.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("Expected only warn and above, got %q", buf.String())
+	}
+}
+
+func TestSetup_RejectsInvalidConfig(t *testing.T) {
+	if err := Setup("loud", "", &bytes.Buffer{}); err == nil {
+		t.Error("Expected invalid level to be rejected")
+	}
+	if err := Setup("", "xml", &bytes.Buffer{}); err == nil {
+		t.Error("Expected invalid format to be rejected")
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	for _, id := range []string{"abc", "3f2a-01:trace.x_y", NewRequestID()} {
+		if !ValidRequestID(id) {
+			t.Errorf("Expected %q to be valid", id)
+		}
+	}
+	for _, id := range []string{"", "has space", "line\nbreak", strings.Repeat("a", 129)} {
+		if ValidRequestID(id) {
+			t.Errorf("Expected %q to be rejected", id)
+		}
+	}
+}
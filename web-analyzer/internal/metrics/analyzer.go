@@ -0,0 +1,34 @@
+package metrics
+
+// Analyzer holds the metrics recorded by an analysis pipeline and its result
+// cache. The server's pipeline records into DefaultAnalyzer; an embedded
+// analyzer can keep its own on a separate registry.
+type Analyzer struct {
+	PhaseDuration    *HistogramVec
+	RenderFallbacks  *CounterVec
+	LinkChecks       *CounterVec
+	LinkCacheHits    *CounterVec
+	LinkCacheMisses  *CounterVec
+	BudgetsExhausted *CounterVec
+	CacheEntries     *Gauge
+}
+
+// NewAnalyzer registers the analysis metrics on r.
+func NewAnalyzer(r *Registry) *Analyzer {
+	return &Analyzer{
+		PhaseDuration: r.NewHistogramVec("webanalyzer_analysis_phase_duration_seconds",
+			"Time spent in each analysis phase (fetch, render, parse, link_check, total).", DefaultBuckets, "phase"),
+		RenderFallbacks: r.NewCounterVec("webanalyzer_render_fallbacks_total",
+			"Pages rendered with the headless browser, by reason and detected bot-protection vendor.", "reason", "vendor"),
+		LinkChecks: r.NewCounterVec("webanalyzer_link_checks_total",
+			"Link checks by outcome category (2xx, 3xx, 4xx, 5xx, timeout, blocked, cancelled, error).", "category"),
+		LinkCacheHits: r.NewCounterVec("webanalyzer_link_cache_hits_total",
+			"Link checks answered from the link-status cache."),
+		LinkCacheMisses: r.NewCounterVec("webanalyzer_link_cache_misses_total",
+			"Cacheable link checks not found in the link-status cache."),
+		BudgetsExhausted: r.NewCounterVec("webanalyzer_budgets_exhausted_total",
+			"Analyses cut short by a budget (max_links, max_duration, max_dom_nodes, max_render_time).", "budget"),
+		CacheEntries: r.NewGauge("webanalyzer_cache_entries",
+			"Results currently held in the cache."),
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_TextFormat(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests.\nSecond line.", "route", "code")
+	size := r.NewGauge("test_size", "Size.")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "phase")
+
+	requests.Inc("/api", "200")
+	requests.Add(2, "/api", "200")
+	requests.Inc(`/q"x`, "500")
+	size.Set(3)
+	latency.Observe(0.05, "fetch")
+	latency.Observe(0.5, "fetch")
+	latency.Observe(5, "fetch")
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	want := `# HELP test_requests_total Requests.\nSecond line.
+# TYPE test_requests_total counter
+test_requests_total{route="/api",code="200"} 3
+test_requests_total{route="/q\"x",code="500"} 1
+# HELP test_size Size.
+# TYPE test_size gauge
+test_size 3
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{phase="fetch",le="0.1"} 1
+test_latency_seconds_bucket{phase="fetch",le="1"} 2
+test_latency_seconds_bucket{phase="fetch",le="+Inf"} 3
+test_latency_seconds_sum{phase="fetch"} 5.55
+test_latency_seconds_count{phase="fetch"} 3
+`
+	if buf.String() != want {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	if got := requests.Value("/api", "200"); got != 3 {
+		t.Errorf("Expected counter value 3, got %v", got)
+	}
+	if got := requests.Value("/missing", "404"); got != 0 {
+		t.Errorf("Expected unseen series to read 0, got %v", got)
+	}
+	if strings.Contains(buf.String(), "/missing") {
+		t.Error("Reading a value must not create a series")
+	}
+}
+
+func TestCounterVec_WrongLabelCountPanics(t *testing.T) {
+	c := NewRegistry().NewCounterVec("test_total", "Test.", "a")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for missing label value")
+		}
+	}()
+	c.Inc()
+}
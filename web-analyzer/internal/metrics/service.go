@@ -0,0 +1,51 @@
+package metrics
+
+// Service metrics exposed on /metrics.
+var (
+	// DefaultAnalyzer is what the server's analyses record.
+	DefaultAnalyzer = NewAnalyzer(Default)
+
+	HTTPRequests = Default.NewCounterVec("webanalyzer_http_requests_total",
+		"HTTP requests handled, by route, method and status code.", "route", "method", "code")
+	HTTPRequestDuration = Default.NewHistogramVec("webanalyzer_http_request_duration_seconds",
+		"HTTP request latency, by route, method and status code.", DefaultBuckets, "route", "method", "code")
+
+	CacheHits = Default.NewCounterVec("webanalyzer_cache_hits_total",
+		"Analyses served from the result cache.")
+	CacheMisses = Default.NewCounterVec("webanalyzer_cache_misses_total",
+		"Cacheable analyses not found in the result cache.")
+	AnalysesCoalesced = Default.NewCounterVec("webanalyzer_analyses_coalesced_total",
+		"Requests that joined an analysis of the same page already in flight.")
+	CacheRevalidations = Default.NewCounterVec("webanalyzer_cache_revalidations_total",
+		"Conditional requests for expired cache entries, by outcome (not_modified, modified, error).", "outcome")
+
+	RateLimitRejections = Default.NewCounterVec("webanalyzer_rate_limit_rejections_total",
+		"Requests rejected by the rate limiter, by route.", "route")
+
+	APIKeyRequests = Default.NewCounterVec("webanalyzer_api_key_requests_total",
+		"Requests admitted per API key.", "key")
+	APIKeyRejections = Default.NewCounterVec("webanalyzer_api_key_rejections_total",
+		"Requests rejected by API key checks (unauthorized, forbidden, rate_limited, quota_exceeded).", "reason")
+)
+
+// Analysis phase label values.
+const (
+	PhaseFetch     = "fetch"
+	PhaseRender    = "render"
+	PhaseParse     = "parse"
+	PhaseLinkCheck = "link_check"
+	PhaseTotal     = "total"
+)
+
+// Render fallback reasons.
+const (
+	RenderReasonBotProtection = "bot_protection"
+	RenderReasonRequested     = "requested"
+)
+
+// Cache revalidation outcomes.
+const (
+	RevalidationNotModified = "not_modified"
+	RevalidationModified    = "modified"
+	RevalidationError       = "error"
+)
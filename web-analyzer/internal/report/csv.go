@@ -0,0 +1,72 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"web-analyzer/internal/analyzer"
+)
+
+// Link statuses reported in exports.
+const (
+	StatusAccessible   = "accessible"
+	StatusInaccessible = "inaccessible"
+	StatusUnchecked    = "unchecked"
+)
+
+// LinkRow is one discovered link with its scope and check status.
+type LinkRow struct {
+	URL        string
+	Label      string
+	Occurrence int
+	Scope      string // "internal" or "external"
+	Status     string
+}
+
+// LinkRows lists every internal and external link of result with the status
+// from the link check. Links that were not checked are StatusUnchecked.
+func LinkRows(result *analyzer.Result) []LinkRow {
+	status := make(map[string]string, len(result.AccessibleLinks)+len(result.InaccessibleLinks))
+	for _, link := range result.AccessibleLinks {
+		status[link.URL] = StatusAccessible
+	}
+	for _, link := range result.InaccessibleLinks {
+		status[link.URL] = StatusInaccessible
+	}
+
+	rows := make([]LinkRow, 0, len(result.InternalLinks)+len(result.ExternalLinks))
+	add := func(links []analyzer.NamedLink, scope string) {
+		for _, link := range links {
+			s, ok := status[link.URL]
+			if !ok {
+				s = StatusUnchecked
+			}
+			rows = append(rows, LinkRow{URL: link.URL, Label: link.Label, Occurrence: link.Occurrence, Scope: scope, Status: s})
+		}
+	}
+	add(result.InternalLinks, "internal")
+	add(result.ExternalLinks, "external")
+	return rows
+}
+
+// WriteCSV writes one row per link: url, label, occurrences, scope, status.
+func WriteCSV(w io.Writer, result *analyzer.Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "label", "occurrences", "scope", "status"})
+	for _, row := range LinkRows(result) {
+		cw.Write([]string{csvSafe(row.URL), csvSafe(row.Label), strconv.Itoa(row.Occurrence), row.Scope, row.Status})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe neutralizes page-controlled cells that a spreadsheet would otherwise
+// evaluate as a formula.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
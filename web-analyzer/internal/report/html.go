@@ -0,0 +1,34 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"sync"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/pkg/embed"
+)
+
+var (
+	htmlOnce sync.Once
+	htmlTmpl *template.Template
+	htmlErr  error
+)
+
+// htmlReport is the data passed to the embedded report template.
+type htmlReport struct {
+	Result *analyzer.Result
+	Links  []LinkRow
+}
+
+// WriteHTML writes a standalone HTML report with inline styles and no
+// external assets, so it can be archived or attached to a CI run.
+func WriteHTML(w io.Writer, result *analyzer.Result) error {
+	htmlOnce.Do(func() {
+		htmlTmpl, htmlErr = embed.LoadEmbeddedTemplateFile("report.html")
+	})
+	if htmlErr != nil {
+		return htmlErr
+	}
+	return htmlTmpl.Execute(w, htmlReport{Result: result, Links: LinkRows(result)})
+}
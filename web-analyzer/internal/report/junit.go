@@ -0,0 +1,82 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"web-analyzer/internal/analyzer"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the link check as a JUnit XML report: every checked link
+// is a test case and each inaccessible link is a failure, so CI systems can
+// list broken links natively.
+func WriteJUnit(w io.Writer, result *analyzer.Result) error {
+	suite := junitTestSuite{
+		Name: result.PageURL,
+		Time: fmt.Sprintf("%.3f", result.AnalysisDuration.Seconds()),
+	}
+	for _, row := range LinkRows(result) {
+		if row.Status == StatusUnchecked {
+			continue
+		}
+		tc := junitTestCase{ClassName: "links." + row.Scope, Name: row.URL}
+		if row.Status == StatusInaccessible {
+			tc.Failure = &junitFailure{
+				Message: "link is not accessible",
+				Type:    "InaccessibleLink",
+				Text:    fmt.Sprintf("%s (label %q, %d occurrence(s)) on %s", row.URL, row.Label, row.Occurrence, result.PageURL),
+			}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	doc := junitTestSuites{
+		Name:     "web-analyzer",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
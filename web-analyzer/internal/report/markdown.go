@@ -0,0 +1,97 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"web-analyzer/internal/analyzer"
+)
+
+// markdownEscaper escapes characters that would break a table cell or inline text.
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`, "\n", " ", "\r", "", "`", "\\`",
+	"*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;",
+)
+
+// WriteMarkdown writes a human-readable summary of result.
+func WriteMarkdown(w io.Writer, result *analyzer.Result) error {
+	bw := bufio.NewWriter(w)
+	md := markdownEscaper.Replace
+
+	fmt.Fprintf(bw, "# Analysis of %s\n\n", md(result.PageURL))
+	fmt.Fprintln(bw, "| Property | Value |")
+	fmt.Fprintln(bw, "| --- | --- |")
+	if result.FinalURL != "" && result.FinalURL != result.PageURL {
+		fmt.Fprintf(bw, "| Final URL | %s |\n", md(result.FinalURL))
+	}
+	fmt.Fprintf(bw, "| Title | %s |\n", md(result.Title))
+	fmt.Fprintf(bw, "| HTML version | %s |\n", md(result.HTMLVersion))
+	fmt.Fprintf(bw, "| Internal links | %d |\n", len(result.InternalLinks))
+	fmt.Fprintf(bw, "| External links | %d |\n", len(result.ExternalLinks))
+	fmt.Fprintf(bw, "| Accessible links | %d |\n", len(result.AccessibleLinks))
+	fmt.Fprintf(bw, "| Inaccessible links | %d |\n", len(result.InaccessibleLinks))
+	if result.LinkCache.Hits > 0 {
+		fmt.Fprintf(bw, "| Link checks from cache | %d |\n", result.LinkCache.Hits)
+	}
+	fmt.Fprintf(bw, "| Login form | %s |\n", yesNo(result.HasLoginForm))
+	fmt.Fprintf(bw, "| Mixed content | %d |\n", len(result.MixedContent))
+	if result.BotProtection != nil {
+		fmt.Fprintf(bw, "| Bot protection | %s (%s) |\n", md(result.BotProtection.Vendor), md(result.BotProtection.Reason))
+	}
+	fmt.Fprintf(bw, "| Rendered | %s |\n", yesNo(result.Rendered))
+	if result.Revalidated {
+		fmt.Fprintln(bw, "| Revalidated | yes |")
+	}
+	if result.Partial {
+		fmt.Fprintln(bw, "| Partial | yes |")
+	}
+	fmt.Fprintf(bw, "| Duration | %.2f seconds |\n", result.AnalysisDuration.Seconds())
+
+	if len(result.Skipped) > 0 {
+		fmt.Fprint(bw, "\n## Skipped\n\n")
+		for _, sk := range result.Skipped {
+			fmt.Fprintf(bw, "- **%s** (%s): %s\n", md(sk.Part), md(sk.Budget), md(sk.Detail))
+		}
+	}
+
+	if len(result.Headings) > 0 {
+		fmt.Fprint(bw, "\n## Headings\n\n")
+		for _, h := range result.Headings {
+			fmt.Fprintf(bw, "- **%s** %s\n", strings.ToUpper(h.Tag), md(h.Title))
+		}
+	}
+
+	if len(result.Redirects) > 0 {
+		fmt.Fprint(bw, "\n## Redirects\n\n")
+		for _, hop := range result.Redirects {
+			fmt.Fprintf(bw, "- %d %s → %s\n", hop.StatusCode, md(hop.URL), md(hop.Location))
+		}
+	}
+
+	if len(result.InaccessibleLinks) > 0 {
+		fmt.Fprint(bw, "\n## Inaccessible links\n\n")
+		for _, link := range result.InaccessibleLinks {
+			fmt.Fprintf(bw, "- %s (%s)\n", md(link.URL), md(link.Label))
+		}
+	}
+
+	if len(result.MixedContent) > 0 {
+		fmt.Fprint(bw, "\n## Mixed content\n\n")
+		fmt.Fprintln(bw, "| URL | Element | Type |")
+		fmt.Fprintln(bw, "| --- | --- | --- |")
+		for _, mc := range result.MixedContent {
+			fmt.Fprintf(bw, "| %s | `<%s %s>` | %s |\n", md(mc.URL), mc.Element, mc.Attribute, mc.Type)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/pkg/errors"
+)
+
+// Format is an export format for an analysis result.
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatJUnit    Format = "junit"
+)
+
+// formatAliases maps the accepted values of the format parameter.
+var formatAliases = map[string]Format{
+	"json":     FormatJSON,
+	"csv":      FormatCSV,
+	"markdown": FormatMarkdown,
+	"md":       FormatMarkdown,
+	"html":     FormatHTML,
+	"junit":    FormatJUnit,
+	"xml":      FormatJUnit,
+}
+
+// mediaTypes maps Accept header media types to formats.
+var mediaTypes = map[string]Format{
+	"application/json":      FormatJSON,
+	"text/csv":              FormatCSV,
+	"text/markdown":         FormatMarkdown,
+	"text/x-markdown":       FormatMarkdown,
+	"text/html":             FormatHTML,
+	"application/xml":       FormatJUnit,
+	"text/xml":              FormatJUnit,
+	"application/junit+xml": FormatJUnit,
+}
+
+// ContentType returns the Content-Type header value for f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatJUnit:
+		return "application/xml; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// Extension returns the file extension used when f is downloaded.
+func (f Format) Extension() string {
+	switch f {
+	case FormatMarkdown:
+		return "md"
+	case FormatJUnit:
+		return "xml"
+	default:
+		return string(f)
+	}
+}
+
+// ParseFormat resolves a format parameter value such as "csv" or "md".
+func ParseFormat(value string) (Format, error) {
+	if f, ok := formatAliases[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return f, nil
+	}
+	return "", &errors.HTTPError{
+		StatusCode: http.StatusBadRequest,
+		Message:    fmt.Sprintf("unsupported format %q: use json, csv, markdown, html or junit", value),
+	}
+}
+
+// Negotiate picks the export format. An explicit format parameter wins;
+// otherwise the highest-weighted supported type in the Accept header is used,
+// falling back to JSON.
+func Negotiate(accept, param string) (Format, error) {
+	if param != "" {
+		return ParseFormat(param)
+	}
+
+	type candidate struct {
+		format Format
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		f, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{f, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return FormatJSON, nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].format, nil
+}
+
+// Write renders result to w in format f.
+func Write(w io.Writer, f Format, result *analyzer.Result) error {
+	switch f {
+	case FormatCSV:
+		return WriteCSV(w, result)
+	case FormatMarkdown:
+		return WriteMarkdown(w, result)
+	case FormatHTML:
+		return WriteHTML(w, result)
+	case FormatJUnit:
+		return WriteJUnit(w, result)
+	default:
+		return json.NewEncoder(w).Encode(result)
+	}
+}
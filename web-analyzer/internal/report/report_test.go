@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/helpers"
+)
+
+func sampleResult() *analyzer.Result {
+	return &analyzer.Result{
+		PageURL:     "https://example.com/",
+		HTMLVersion: "HTML5",
+		Title:       "Example | <Home>",
+		Headings:    []analyzer.Heading{{Tag: "h1", Title: "Welcome"}},
+		InternalLinks: []analyzer.NamedLink{
+			{URL: "https://example.com/about", Label: "About", Occurrence: 2},
+			{URL: "https://example.com/gone", Label: "=HYPERLINK(\"x\")", Occurrence: 1},
+		},
+		ExternalLinks: []analyzer.NamedLink{
+			{URL: "https://other.example/", Label: "Other", Occurrence: 1},
+		},
+		AccessibleLinks:   []analyzer.NamedLink{{URL: "https://example.com/about", Label: "About", Occurrence: 2}},
+		InaccessibleLinks: []analyzer.NamedLink{{URL: "https://example.com/gone", Label: "=HYPERLINK(\"x\")", Occurrence: 1}},
+		BotProtection:     &helpers.BotDetection{Vendor: "Cloudflare", Reason: "cf-mitigated header"},
+		AnalysisDuration:  1500 * time.Millisecond,
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept, param string
+		want          Format
+	}{
+		{"", "", FormatJSON},
+		{"*/*", "", FormatJSON},
+		{"text/csv", "", FormatCSV},
+		{"text/markdown;q=0.5, application/xml", "", FormatJUnit},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", FormatHTML},
+		{"image/png", "", FormatJSON},
+		{"text/csv", "md", FormatMarkdown},
+		{"", "JUNIT", FormatJUnit},
+	}
+	for _, c := range cases {
+		got, err := Negotiate(c.accept, c.param)
+		if err != nil || got != c.want {
+			t.Errorf("Negotiate(%q, %q) = %q, %v; want %q", c.accept, c.param, got, err, c.want)
+		}
+	}
+
+	if _, err := Negotiate("", "pdf"); err == nil {
+		t.Error("Expected unknown format to be rejected")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, sampleResult()); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Expected header and 3 links, got %d rows", len(rows))
+	}
+	want := [][]string{
+		{"url", "label", "occurrences", "scope", "status"},
+		{"https://example.com/about", "About", "2", "internal", "accessible"},
+		{"https://example.com/gone", "'=HYPERLINK(\"x\")", "1", "internal", "inaccessible"},
+		{"https://other.example/", "Other", "1", "external", "unchecked"},
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %v, want %v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, sampleResult()); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Analysis of https://example.com/",
+		`| Title | Example \| &lt;Home&gt; |`,
+		"| Bot protection | Cloudflare (cf-mitigated header) |",
+		"## Inaccessible links",
+		"- **H1** Welcome",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected Markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, sampleResult()); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "cdn.") || strings.Contains(out, "<script") || strings.Contains(out, "stylesheet") {
+		t.Error("Expected report to be self-contained")
+	}
+	if !strings.Contains(out, "Example | &lt;Home&gt;") {
+		t.Error("Expected page-controlled text to be escaped")
+	}
+	if !strings.Contains(out, `<span class="badge inaccessible">inaccessible</span>`) {
+		t.Error("Expected link status badges")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, sampleResult()); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	if doc.Tests != 2 || doc.Failures != 1 || len(doc.Suites) != 1 {
+		t.Fatalf("Expected 2 tests with 1 failure, got %+v", doc)
+	}
+	for _, tc := range doc.Suites[0].TestCases {
+		failed := tc.Failure != nil
+		if failed != (tc.Name == "https://example.com/gone") {
+			t.Errorf("Unexpected failure state for %s: %v", tc.Name, failed)
+		}
+	}
+}
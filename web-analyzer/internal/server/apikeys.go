@@ -0,0 +1,451 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/metrics"
+	"web-analyzer/pkg/api"
+	"web-analyzer/pkg/errors"
+)
+
+// APIKeyHeader carries the caller's API key; "Authorization: Bearer <key>"
+// is accepted as well.
+const APIKeyHeader = "X-API-Key"
+
+// Feature is an optional capability granted to an API key.
+type Feature string
+
+const (
+	// FeatureBatch allows /api/batch.
+	FeatureBatch Feature = "batch"
+	// FeatureRender allows headless rendering. Without it, renderMode
+	// "always" is refused and "auto" analyzes the fetched HTML only.
+	FeatureRender Feature = "render"
+)
+
+// APIKeyConfig describes one key, as read from the keys file or posted to
+// the admin endpoint.
+type APIKeyConfig struct {
+	ID         string    `json:"id"`
+	Key        string    `json:"key,omitempty"`
+	RateLimit  string    `json:"rateLimit,omitempty"`  // "N/unit[,burst]"; empty means unlimited
+	DailyQuota int       `json:"dailyQuota,omitempty"` // requests per UTC day, a batch counting one per URL; 0 means unlimited
+	Features   []Feature `json:"features,omitempty"`
+}
+
+// APIKeyUsage counts the requests admitted for a key.
+type APIKeyUsage struct {
+	Day      string    `json:"day"`
+	Today    int       `json:"today"`
+	Total    int64     `json:"total"`
+	Rejected int64     `json:"rejected"`
+	LastUsed time.Time `json:"lastUsed,omitempty"`
+}
+
+// APIKeyInfo is a key's configuration and usage without its secret.
+type APIKeyInfo struct {
+	APIKeyConfig
+	Usage APIKeyUsage `json:"usage"`
+}
+
+type apiKey struct {
+	store    *APIKeyStore
+	cfg      APIKeyConfig // Key is cleared once hashed
+	hash     [sha256.Size]byte
+	features map[Feature]bool
+	limiter  *RateLimiter
+	usage    APIKeyUsage
+}
+
+// APIKeyStore holds the API keys and their usage in memory.
+type APIKeyStore struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	byID   map[string]*apiKey
+	byHash map[[sha256.Size]byte]*apiKey
+}
+
+// NewAPIKeyStore returns an empty store.
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		now:    time.Now,
+		byID:   make(map[string]*apiKey),
+		byHash: make(map[[sha256.Size]byte]*apiKey),
+	}
+}
+
+// LoadAPIKeys reads a JSON file of the form {"keys": [APIKeyConfig...]}.
+func LoadAPIKeys(path string) (*APIKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Keys []APIKeyConfig `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", path, err)
+	}
+	s := NewAPIKeyStore()
+	for _, cfg := range file.Keys {
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("API key %q has no key", cfg.ID)
+		}
+		if _, err := s.Add(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add registers a key, generating its secret when cfg.Key is empty, and
+// returns the secret.
+func (s *APIKeyStore) Add(cfg APIKeyConfig) (string, error) {
+	if cfg.ID == "" {
+		return "", &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "API key id is required"}
+	}
+	if cfg.DailyQuota < 0 {
+		return "", &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "dailyQuota must not be negative"}
+	}
+	key := &apiKey{store: s, features: make(map[Feature]bool)}
+	for _, f := range cfg.Features {
+		if f != FeatureBatch && f != FeatureRender {
+			return "", &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("unknown feature %q", f)}
+		}
+		key.features[f] = true
+	}
+	if cfg.RateLimit != "" {
+		rate, burst, err := ParseRateLimit(cfg.RateLimit)
+		if err != nil {
+			return "", &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: err.Error()}
+		}
+		key.limiter = NewRateLimiter(RateLimitConfig{Route: "api_key", Rate: rate, Burst: burst})
+		key.limiter.now = func() time.Time { return s.now() }
+	}
+
+	secret := cfg.Key
+	if secret == "" {
+		b := make([]byte, 24)
+		rand.Read(b)
+		secret = "wa_" + hex.EncodeToString(b)
+	}
+	key.hash = sha256.Sum256([]byte(secret))
+	cfg.Key = ""
+	key.cfg = cfg
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[cfg.ID]; ok {
+		key.close()
+		return "", &errors.HTTPError{StatusCode: http.StatusConflict, Message: fmt.Sprintf("API key %q already exists", cfg.ID)}
+	}
+	if _, ok := s.byHash[key.hash]; ok {
+		key.close()
+		return "", &errors.HTTPError{StatusCode: http.StatusConflict, Message: "key is already in use"}
+	}
+	s.byID[cfg.ID] = key
+	s.byHash[key.hash] = key
+	return secret, nil
+}
+
+// Remove deletes the key with id and reports whether it existed.
+func (s *APIKeyStore) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.byID[id]
+	if !ok {
+		return false
+	}
+	delete(s.byID, id)
+	delete(s.byHash, key.hash)
+	key.close()
+	return true
+}
+
+// List returns every key with its usage, ordered by id.
+func (s *APIKeyStore) List() []APIKeyInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]APIKeyInfo, 0, len(s.byID))
+	for _, key := range s.byID {
+		infos = append(infos, s.infoLocked(key))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Info returns the key with id.
+func (s *APIKeyStore) Info(id string) (APIKeyInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.byID[id]
+	if !ok {
+		return APIKeyInfo{}, false
+	}
+	return s.infoLocked(key), true
+}
+
+func (s *APIKeyStore) infoLocked(key *apiKey) APIKeyInfo {
+	s.rollDayLocked(key)
+	return APIKeyInfo{APIKeyConfig: key.cfg, Usage: key.usage}
+}
+
+// rollDayLocked resets the daily counter when the UTC day has changed.
+func (s *APIKeyStore) rollDayLocked(key *apiKey) {
+	if day := s.now().UTC().Format(time.DateOnly); key.usage.Day != day {
+		key.usage.Day, key.usage.Today = day, 0
+	}
+}
+
+func (k *apiKey) close() {
+	if k.limiter != nil {
+		k.limiter.Close()
+	}
+}
+
+// Middleware requires a valid API key, applies the key's rate limit and
+// daily quota, counts its usage and makes the key available to handlers
+// through the request context.
+func (s *APIKeyStore) Middleware(next http.Handler) http.Handler {
+	return s.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Context().Value(apiKeyContextKey{}).(*apiKey)
+		if key.limiter != nil && !key.limiter.admit(w, r, key.cfg.ID) {
+			s.reject(key, "rate_limited")
+			return
+		}
+		if !s.consumeQuota(w, r, key, 1, 0) {
+			s.reject(key, "quota_exceeded")
+			return
+		}
+		metrics.APIKeyRequests.Inc(key.cfg.ID)
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// Authenticate requires a valid API key without counting the request against
+// its limits, for endpoints such as usage reporting.
+func (s *APIKeyStore) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := presentedKey(r)
+		s.mu.Lock()
+		key, ok := s.byHash[sha256.Sum256([]byte(secret))]
+		s.mu.Unlock()
+		if secret == "" || !ok {
+			metrics.APIKeyRejections.Inc("unauthorized")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="web-analyzer"`)
+			writeError(w, r, &errors.HTTPError{StatusCode: http.StatusUnauthorized, Message: "A valid API key is required"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	})
+}
+
+// consumeQuota counts units requests against the key's daily quota, of which
+// prepaid were already counted for this request, or writes a 429 lasting until
+// the next UTC midnight when fewer are left. A refused request gets its
+// prepaid units back.
+func (s *APIKeyStore) consumeQuota(w http.ResponseWriter, r *http.Request, key *apiKey, units, prepaid int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollDayLocked(key)
+	prepaid = min(prepaid, key.usage.Today)
+	quota := key.cfg.DailyQuota
+	if quota > 0 {
+		w.Header().Set("X-Quota-Limit", strconv.Itoa(quota))
+		if left := quota - key.usage.Today + prepaid; units > left {
+			key.usage.Today -= prepaid
+			key.usage.Total -= int64(prepaid)
+			now := s.now().UTC()
+			midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			w.Header().Set("X-Quota-Remaining", strconv.Itoa(max(left, 0)))
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(midnight.Sub(now))))
+			msg := "Daily quota exceeded. Try again tomorrow."
+			if units > 1 && left > 0 {
+				msg = fmt.Sprintf("A batch of %d URLs exceeds the %d requests left in the daily quota.", units, left)
+			}
+			writeError(w, r, &errors.HTTPError{StatusCode: http.StatusTooManyRequests, Message: msg})
+			return false
+		}
+		w.Header().Set("X-Quota-Remaining", strconv.Itoa(quota-key.usage.Today+prepaid-units))
+	}
+	key.usage.Today += units - prepaid
+	key.usage.Total += int64(units - prepaid)
+	key.usage.LastUsed = s.now()
+	return true
+}
+
+// consumeBatchQuota counts a batch of n URLs against the calling key's daily
+// quota as n requests; the middleware has already counted one. A batch larger
+// than what is left of the quota is refused whole.
+func consumeBatchQuota(w http.ResponseWriter, r *http.Request, n int) bool {
+	key, ok := r.Context().Value(apiKeyContextKey{}).(*apiKey)
+	if !ok {
+		return true
+	}
+	if !key.store.consumeQuota(w, r, key, n, 1) {
+		key.store.reject(key, "quota_exceeded")
+		return false
+	}
+	return true
+}
+
+func (s *APIKeyStore) reject(key *apiKey, reason string) {
+	metrics.APIKeyRejections.Inc(reason)
+	s.mu.Lock()
+	key.usage.Rejected++
+	s.mu.Unlock()
+}
+
+// presentedKey returns the key from X-API-Key or an Authorization bearer token.
+func presentedKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	return bearerToken(r)
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type apiKeyContextKey struct{}
+
+// APIKeyID returns the id of the API key that authenticated ctx's request.
+func APIKeyID(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*apiKey)
+	if !ok {
+		return "", false
+	}
+	return key.cfg.ID, true
+}
+
+// featureAllowed reports whether ctx's request may use f. Requests without
+// an API key, when keys are not configured, may use everything.
+func featureAllowed(ctx context.Context, f Feature) bool {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*apiKey)
+	return !ok || key.features[f]
+}
+
+// RequireFeature rejects requests whose API key lacks f with 403.
+func RequireFeature(f Feature) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !featureAllowed(r.Context(), f) {
+				metrics.APIKeyRejections.Inc("forbidden")
+				writeError(w, r, &errors.HTTPError{StatusCode: http.StatusForbidden, Message: fmt.Sprintf("API key does not allow %s", f)})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// restrictRendering applies FeatureRender to opts: without it an explicit
+// render is refused and automatic rendering is turned off.
+func restrictRendering(ctx context.Context, opts analyzer.AnalyzeOptions) (analyzer.AnalyzeOptions, error) {
+	if featureAllowed(ctx, FeatureRender) || opts.RenderMode == analyzer.RenderNever {
+		return opts, nil
+	}
+	if opts.RenderMode == analyzer.RenderAlways {
+		metrics.APIKeyRejections.Inc("forbidden")
+		return opts, &errors.HTTPError{StatusCode: http.StatusForbidden, Message: "API key does not allow render"}
+	}
+	opts.RenderMode = analyzer.RenderNever
+	return opts, nil
+}
+
+// HandleUsage returns the calling key's configuration and usage.
+func (s *APIKeyStore) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	id, ok := APIKeyID(r.Context())
+	if !ok {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusUnauthorized, Message: "A valid API key is required"})
+		return
+	}
+	info, ok := s.Info(id)
+	if !ok {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusNotFound, Message: "API key was removed"})
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, api.Version+"/") {
+		writeJSON(w, http.StatusOK, usageToV1(info))
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// HandleAdminKeys lists keys on GET and creates one on POST. The response to
+// a POST is the only time a generated secret is shown.
+func (s *APIKeyStore) HandleAdminKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.List())
+	case http.MethodPost:
+		var cfg APIKeyConfig
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 64<<10)).Decode(&cfg); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		secret, err := s.Add(cfg)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		info, _ := s.Info(cfg.ID)
+		info.Key = secret
+		writeJSON(w, http.StatusCreated, info)
+	default:
+		http.Error(w, "Only GET and POST allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleAdminKey deletes the key named by the {id} path value.
+func (s *APIKeyStore) HandleAdminKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.Remove(r.PathValue("id")) {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminAuth requires "Authorization: Bearer <token>" for the admin endpoints.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="web-analyzer-admin"`)
+				http.Error(w, "Admin token required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKeyStore(t *testing.T, cfgs ...APIKeyConfig) (*APIKeyStore, *time.Time) {
+	t.Helper()
+	s := NewAPIKeyStore()
+	now := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	for _, cfg := range cfgs {
+		if _, err := s.Add(cfg); err != nil {
+			t.Fatalf("Add(%s) failed: %v", cfg.ID, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, info := range s.List() {
+			s.Remove(info.ID)
+		}
+	})
+	return s, &now
+}
+
+func serveWithKey(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestAPIKeyMiddleware_RejectsMissingAndUnknownKeys(t *testing.T) {
+	s, _ := newTestKeyStore(t, APIKeyConfig{ID: "team-a", Key: "secret-a"})
+	h := s.Middleware(okHandler)
+
+	for _, key := range []string{"", "wrong"} {
+		if rec := serveWithKey(h, key, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Key %q: expected 401, got %d", key, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer secret-a")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected bearer key to be accepted, got %d", rec.Code)
+	}
+}
+
+func TestAPIKeyMiddleware_RateLimitAndDailyQuota(t *testing.T) {
+	s, now := newTestKeyStore(t,
+		APIKeyConfig{ID: "limited", Key: "k1", RateLimit: "1/s,1"},
+		APIKeyConfig{ID: "quota", Key: "k2", DailyQuota: 2},
+	)
+	h := s.Middleware(okHandler)
+
+	if rec := serveWithKey(h, "k1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d", rec.Code)
+	}
+	if rec := serveWithKey(h, "k1", ""); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d", rec.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := serveWithKey(h, "k2", ""); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := serveWithKey(h, "k2", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected quota rejection, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "3600" {
+		t.Errorf("Expected Retry-After until UTC midnight (3600), got %q", got)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if rec := serveWithKey(h, "k2", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected quota to reset on a new day, got %d", rec.Code)
+	}
+
+	info, _ := s.Info("quota")
+	if info.Usage.Today != 1 || info.Usage.Total != 3 || info.Usage.Rejected != 1 || info.Usage.Day != "2025-01-02" {
+		t.Errorf("Unexpected usage: %+v", info.Usage)
+	}
+}
+
+func TestHandleBatch_ChargesQuotaPerURL(t *testing.T) {
+	s, _ := newTestKeyStore(t, APIKeyConfig{ID: "quota", Key: "k", DailyQuota: 5})
+	h := s.Middleware(http.HandlerFunc(HandleBatch))
+	batch := func(n int) *httptest.ResponseRecorder {
+		urls := make([]string, n)
+		for i := range urls {
+			urls[i] = "://not-a-url"
+		}
+		body, _ := json.Marshal(map[string]any{"urls": urls})
+		req := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(APIKeyHeader, "k")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := batch(3); rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Remaining") != "2" {
+		t.Fatalf("Expected a 3-URL batch to pass with 2 left, got %d remaining %q", rec.Code, rec.Header().Get("X-Quota-Remaining"))
+	}
+	rec := batch(3)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("X-Quota-Remaining") != "2" {
+		t.Fatalf("Expected a batch over the remaining quota to be refused, got %d remaining %q", rec.Code, rec.Header().Get("X-Quota-Remaining"))
+	}
+	if !strings.Contains(rec.Body.String(), "3 URLs") {
+		t.Errorf("Expected the batch size in the message, got %q", rec.Body.String())
+	}
+	if rec := batch(2); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the refused batch not to use quota, got %d", rec.Code)
+	}
+
+	info, _ := s.Info("quota")
+	if info.Usage.Today != 5 || info.Usage.Total != 5 || info.Usage.Rejected != 1 {
+		t.Errorf("Expected 5 URLs charged and 1 rejection, got %+v", info.Usage)
+	}
+}
+
+func TestAPIKeyFeatures(t *testing.T) {
+	s, _ := newTestKeyStore(t,
+		APIKeyConfig{ID: "basic", Key: "basic"},
+		APIKeyConfig{ID: "full", Key: "full", Features: []Feature{FeatureBatch, FeatureRender}},
+	)
+
+	batch := s.Middleware(RequireFeature(FeatureBatch)(okHandler))
+	if rec := serveWithKey(batch, "basic", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for batch without the feature, got %d", rec.Code)
+	}
+	if rec := serveWithKey(batch, "full", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected batch to be allowed, got %d", rec.Code)
+	}
+
+	var gotMode string
+	analyze := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, opts, _ := parseAnalyzeRequest(r)
+		opts, err := restrictRendering(r.Context(), opts)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		gotMode = string(opts.RenderMode)
+	}))
+	if rec := serveWithKey(analyze, "basic", `{"url":"https://example.com","options":{"renderMode":"always"}}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for renderMode always without the feature, got %d", rec.Code)
+	}
+	serveWithKey(analyze, "basic", `{"url":"https://example.com"}`)
+	if gotMode != "never" {
+		t.Errorf("Expected automatic rendering to be disabled, got %q", gotMode)
+	}
+	serveWithKey(analyze, "full", `{"url":"https://example.com"}`)
+	if gotMode != "" {
+		t.Errorf("Expected automatic rendering to stay on, got %q", gotMode)
+	}
+}
+
+func TestAPIKeyStore_AddValidation(t *testing.T) {
+	s, _ := newTestKeyStore(t, APIKeyConfig{ID: "a", Key: "k"})
+	for _, cfg := range []APIKeyConfig{
+		{},
+		{ID: "a"},
+		{ID: "b", Key: "k"},
+		{ID: "c", Features: []Feature{"crawl"}},
+		{ID: "d", RateLimit: "fast"},
+		{ID: "e", DailyQuota: -1},
+	} {
+		if _, err := s.Add(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(path, []byte(`{"keys":[{"id":"team-a","key":"secret","dailyQuota":10,"features":["batch"]}]}`), 0o600)
+
+	s, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("LoadAPIKeys failed: %v", err)
+	}
+	infos := s.List()
+	if len(infos) != 1 || infos[0].ID != "team-a" || infos[0].Key != "" || infos[0].DailyQuota != 10 {
+		t.Errorf("Unexpected keys: %+v", infos)
+	}
+}
+
+func TestAdminKeyEndpoints(t *testing.T) {
+	s, _ := newTestKeyStore(t)
+	mux := http.NewServeMux()
+	admin := AdminAuth("admin-token")
+	mux.Handle("/admin/keys", admin(http.HandlerFunc(s.HandleAdminKeys)))
+	mux.Handle("/admin/keys/{id}", admin(http.HandlerFunc(s.HandleAdminKey)))
+	mux.Handle("/api/usage", s.Authenticate(http.HandlerFunc(s.HandleUsage)))
+	mux.Handle("/api/v1/usage", s.Authenticate(http.HandlerFunc(s.HandleUsage)))
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/admin/keys", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the admin token, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/admin/keys", "admin-token", `{"id":"team-b","dailyQuota":5}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var created APIKeyInfo
+	json.NewDecoder(rec.Body).Decode(&created)
+	if !strings.HasPrefix(created.Key, "wa_") {
+		t.Fatalf("Expected a generated key, got %q", created.Key)
+	}
+
+	rec = do(http.MethodGet, "/api/usage", created.Key, "")
+	var usage APIKeyInfo
+	json.NewDecoder(rec.Body).Decode(&usage)
+	if rec.Code != http.StatusOK || usage.ID != "team-b" || usage.Key != "" {
+		t.Errorf("Unexpected usage response %d: %+v", rec.Code, usage)
+	}
+
+	if rec := do(http.MethodGet, "/api/v1/usage", created.Key, ""); !strings.Contains(rec.Body.String(), `"daily_quota":5`) {
+		t.Errorf("Expected snake_case usage on /api/v1, got %s", rec.Body)
+	}
+
+	if rec := do(http.MethodGet, "/admin/keys", "admin-token", ""); !strings.Contains(rec.Body.String(), `"team-b"`) || strings.Contains(rec.Body.String(), created.Key) {
+		t.Errorf("Expected key listed without its secret, got %s", rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/admin/keys/team-b", "admin-token", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/usage", created.Key, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected deleted key to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiter_SkipsRequestsWithAPIKey(t *testing.T) {
+	s, _ := newTestKeyStore(t, APIKeyConfig{ID: "a", Key: "k"})
+	l, _ := newTestLimiter(t, RateLimitConfig{Rate: 1, Burst: 1})
+	h := s.Middleware(l.Middleware(okHandler))
+	for i := 0; i < 3; i++ {
+		if rec := serveWithKey(h, "k", ""); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected the per-IP limit to be skipped, got %d", i, rec.Code)
+		}
+	}
+}
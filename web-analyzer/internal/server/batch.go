@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/constants"
+	"web-analyzer/pkg/errors"
+)
+
+// batchRequest is the JSON body accepted by the batch endpoint.
+type batchRequest struct {
+	URLs    []string            `json:"urls"`
+	Options *analyzeOptionsJSON `json:"options,omitempty"`
+}
+
+// batchLine is one NDJSON line of batch output.
+type batchLine struct {
+	Index  int              `json:"index"`
+	URL    string           `json:"url"`
+	Result *analyzer.Result `json:"result,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// HandleBatch analyzes a list of URLs on a bounded worker pool and streams one
+// NDJSON line per URL as it completes. A failing URL produces an inline error
+// line instead of aborting the batch.
+func HandleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	urls, opts, err := parseBatchRequest(r, decodeBatchJSON, decodeBatchValues)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if opts, err = restrictRendering(r.Context(), opts); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !consumeBatchQuota(w, r, len(urls)) {
+		return
+	}
+
+	serveBatch(w, r, urls, opts, func(idx int, result *analyzer.Result, err error) any {
+		line := batchLine{Index: idx, URL: urls[idx], Result: result}
+		if err != nil {
+			line.Error = err.Error()
+		}
+		return line
+	})
+}
+
+// serveBatch analyzes urls on a bounded worker pool and streams the NDJSON
+// line that newLine builds for each URL as it completes.
+func serveBatch(w http.ResponseWriter, r *http.Request, urls []string, opts analyzer.AnalyzeOptions, newLine func(idx int, result *analyzer.Result, err error) any) {
+	refresh, recheck := cacheBypass(r), recheckLinks(r)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher := http.NewResponseController(w)
+
+	var writeMu sync.Mutex
+	enc := json.NewEncoder(w)
+	emit := func(line any) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		enc.Encode(line)
+		flusher.Flush()
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(constants.BatchWorkers, len(urls)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				pageURL, itemOpts, err := withURLCredentials(urls[idx], opts)
+				var result *analyzer.Result
+				if err == nil {
+					result, err = analyzeCached(r.Context(), pageURL, itemOpts, refresh, recheck)
+				}
+				emit(newLine(idx, result, err))
+			}
+		}()
+	}
+
+dispatch:
+	for idx := range urls {
+		select {
+		case jobs <- idx:
+		case <-r.Context().Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// parseBatchRequest reads the URL list from a JSON body decoded by decodeJSON,
+// an uploaded file (multipart field "file"), or a plain-text body with one URL
+// per line. Uploads and plain-text lists take their options from the form and
+// query values, read by decodeValues.
+func parseBatchRequest(
+	r *http.Request,
+	decodeJSON func(io.Reader) ([]string, analyzer.AnalyzeOptions, error),
+	decodeValues func(url.Values) (analyzer.AnalyzeOptions, error),
+) ([]string, analyzer.AnalyzeOptions, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, 4<<20)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var urls []string
+	var opts analyzer.AnalyzeOptions
+	switch mediaType {
+	case "application/json":
+		var err error
+		if urls, opts, err = decodeJSON(r.Body); err != nil {
+			return nil, opts, err
+		}
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, opts, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "missing upload field \"file\""}
+		}
+		defer file.Close()
+		if urls, err = readURLList(file); err != nil {
+			return nil, opts, err
+		}
+		if opts, err = decodeValues(r.Form); err != nil {
+			return nil, opts, err
+		}
+	default:
+		var err error
+		if urls, err = readURLList(r.Body); err != nil {
+			return nil, opts, err
+		}
+		if opts, err = decodeValues(r.URL.Query()); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil, opts, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "at least one URL is required"}
+	}
+	if len(urls) > constants.MaxBatchURLs {
+		return nil, opts, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("a batch may contain at most %d URLs", constants.MaxBatchURLs)}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, opts, err
+	}
+	return urls, opts, nil
+}
+
+// decodeBatchJSON reads a batchRequest body.
+func decodeBatchJSON(body io.Reader) ([]string, analyzer.AnalyzeOptions, error) {
+	var req batchRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, analyzer.AnalyzeOptions{}, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid JSON body: " + err.Error()}
+	}
+	return trimURLs(req.URLs), req.Options.toOptions(), nil
+}
+
+// decodeBatchValues reads batch options named as in batchRequest from form or
+// query values.
+func decodeBatchValues(values url.Values) (analyzer.AnalyzeOptions, error) {
+	var opts analyzeOptionsJSON
+	if err := decodeOptionValues(values, &opts); err != nil {
+		return analyzer.AnalyzeOptions{}, err
+	}
+	return opts.toOptions(), nil
+}
+
+// trimURLs drops surrounding whitespace and empty entries.
+func trimURLs(in []string) []string {
+	var urls []string
+	for _, u := range in {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// readURLList reads one URL per line, skipping blank lines and # comments.
+func readURLList(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "failed to read URL list: " + err.Error()}
+	}
+	return urls, nil
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/testutil"
+)
+
+func init() {
+	analyzer.LoadTagConfig = func() (*analyzer.TagConfig, error) {
+		return &analyzer.TagConfig{Headings: []string{"h1", "h2", "h3"}}, nil
+	}
+}
+
+func newPageServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><title>Page " + r.URL.Path + "</title></html>"))
+	}))
+}
+
+func decodeBatchLines(t *testing.T, body []byte) map[int]batchLine {
+	t.Helper()
+	lines := make(map[int]batchLine)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var line batchLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines[line.Index] = line
+	}
+	return lines
+}
+
+func TestHandleBatch_JSONStreamsInlineErrors(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer()
+	defer pages.Close()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"urls":    []string{pages.URL + "/a", "://not-a-url", pages.URL + "/b"},
+		"options": map[string]interface{}{"checkLinks": false},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	HandleBatch(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected 200 NDJSON, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	lines := decodeBatchLines(t, rec.Body.Bytes())
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %s", len(lines), rec.Body.String())
+	}
+	if lines[0].Result == nil || lines[0].Result.Title != "Page /a" {
+		t.Errorf("Expected result for first URL, got %+v", lines[0])
+	}
+	if lines[1].Error == "" || lines[1].Result != nil {
+		t.Errorf("Expected inline error for invalid URL, got %+v", lines[1])
+	}
+	if lines[2].Result == nil || lines[2].Result.Title != "Page /b" {
+		t.Errorf("Expected result for third URL, got %+v", lines[2])
+	}
+}
+
+func TestHandleBatch_FileUpload(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer()
+	defer pages.Close()
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, _ := form.CreateFormFile("file", "urls.txt")
+	part.Write([]byte("# pages to audit\n" + pages.URL + "/one\n\n" + pages.URL + "/two\n"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/batch", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	HandleBatch(rec, req)
+
+	lines := decodeBatchLines(t, rec.Body.Bytes())
+	if len(lines) != 2 || lines[0].URL != pages.URL+"/one" || lines[1].URL != pages.URL+"/two" {
+		t.Errorf("Expected both uploaded URLs analyzed, got %s", rec.Body.String())
+	}
+}
+
+func TestHandleBatch_OptionsFromFormAndQuery(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var mu sync.Mutex
+	agents := make(map[string]string)
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.URL.Path] = r.UserAgent()
+		mu.Unlock()
+		w.Write([]byte("<html><title>Page</title></html>"))
+	}))
+	defer pages.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/batch?userAgent=list-bot&checkLinks=false", strings.NewReader(pages.URL+"/list\n"))
+	req.Header.Set("Content-Type", "text/plain")
+	HandleBatch(httptest.NewRecorder(), req)
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	form.WriteField("user_agent", "upload-bot")
+	part, _ := form.CreateFormFile("file", "urls.txt")
+	part.Write([]byte(pages.URL + "/upload\n"))
+	form.Close()
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/batch", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	HandleBatchV1(httptest.NewRecorder(), req)
+
+	if agents["/list"] != "list-bot" || agents["/upload"] != "upload-bot" {
+		t.Errorf("Expected the options from the query and the form, got %v", agents)
+	}
+
+	for _, query := range []string{"renderMode=sometimes", "maxLinks=many", "headers=X-A"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/batch?"+query, strings.NewReader(pages.URL+"\n"))
+		rec := httptest.NewRecorder()
+		HandleBatch(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleBatch_RejectsEmptyAndOversized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader("\n# nothing\n"))
+	rec := httptest.NewRecorder()
+	HandleBatch(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty batch, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(strings.Repeat("https://example.com\n", 501)))
+	rec = httptest.NewRecorder()
+	HandleBatch(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for oversized batch, got %d", rec.Code)
+	}
+}
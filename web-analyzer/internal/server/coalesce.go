@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/metrics"
+)
+
+// flight is one analysis shared by every caller that asked for the same key
+// while it ran.
+type flight struct {
+	done    chan struct{}
+	result  *analyzer.Result
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup deduplicates concurrent analyses, in the style of singleflight.
+// The analysis runs detached from any one caller: a caller that goes away
+// stops waiting, and the analysis is only cancelled once nobody is waiting.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// analyses coalesces the cacheable analyses started by the handlers.
+var analyses = &flightGroup{flights: make(map[string]*flight)}
+
+// do runs fn once for all concurrent callers with the same key and returns
+// its outcome to each of them. fn's context keeps ctx's values, such as the
+// request ID, but not its cancellation.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*analyzer.Result, error)) (*analyzer.Result, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if ok {
+		f.waiters++
+		metrics.AnalysesCoalesced.Inc()
+	} else {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.flights[key] = f
+		go g.run(flightCtx, key, f, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		g.leave(key, f)
+		return nil, helpers.ContextError(ctx, "analysis")
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, f *flight, fn func(context.Context) (*analyzer.Result, error)) {
+	defer f.cancel()
+	f.result, f.err = fn(ctx)
+
+	g.mu.Lock()
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+	g.mu.Unlock()
+	close(f.done)
+}
+
+// leave drops a waiter that gave up, cancelling the analysis when it was the
+// last one. The flight is forgotten so later callers start afresh rather than
+// joining a cancelled analysis.
+func (g *flightGroup) leave(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f.waiters--
+	if f.waiters == 0 {
+		if g.flights[key] == f {
+			delete(g.flights, key)
+		}
+		f.cancel()
+	}
+}
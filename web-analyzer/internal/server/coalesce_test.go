@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/metrics"
+	"web-analyzer/internal/testutil"
+)
+
+// newBlockingPageServer serves a page once release is closed and signals
+// cancelled when a request is abandoned.
+func newBlockingPageServer() (ts *httptest.Server, hits *atomic.Int32, release, cancelled chan struct{}) {
+	hits = new(atomic.Int32)
+	release, cancelled = make(chan struct{}), make(chan struct{}, 10)
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		select {
+		case <-release:
+			w.Write([]byte("<html><title>Shared</title></html>"))
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	}))
+	return ts, hits, release, cancelled
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAnalyzeCached_CoalescesConcurrentRequests(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages, hits, release, _ := newBlockingPageServer()
+	defer pages.Close()
+	opts := analyzer.AnalyzeOptions{SkipLinkCheck: true}
+
+	coalescedBefore := metrics.AnalysesCoalesced.Value()
+	const callers = 5
+	results := make([]*analyzer.Result, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Equivalent spellings of the URL share the analysis too
+			pageURL := pages.URL
+			if i%2 == 1 {
+				pageURL += "/"
+			}
+			results[i], _ = analyzeCached(context.Background(), pageURL, opts, false, false)
+		}()
+	}
+	waitFor(t, "callers to join", func() bool { return metrics.AnalysesCoalesced.Value()-coalescedBefore == callers-1 })
+	close(release)
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Errorf("Expected one fetch for %d callers, got %d", callers, hits.Load())
+	}
+	for i, r := range results {
+		if r == nil || r != results[0] {
+			t.Errorf("Caller %d: expected the shared result, got %v", i, r)
+		}
+	}
+}
+
+func TestAnalyzeCached_CancelledCallerDoesNotCancelOthers(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages, hits, release, cancelled := newBlockingPageServer()
+	defer pages.Close()
+	opts := analyzer.AnalyzeOptions{SkipLinkCheck: true}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := analyzeCached(ctx, pages.URL+"/cancel", opts, true, false)
+		firstErr <- err
+	}()
+	waitFor(t, "the fetch to start", func() bool { return hits.Load() == 1 })
+
+	second := make(chan *analyzer.Result, 1)
+	coalescedBefore := metrics.AnalysesCoalesced.Value()
+	go func() {
+		result, _ := analyzeCached(context.Background(), pages.URL+"/cancel", opts, true, false)
+		second <- result
+	}()
+	waitFor(t, "the second caller to join", func() bool { return metrics.AnalysesCoalesced.Value() > coalescedBefore })
+
+	cancel()
+	if err := <-firstErr; err == nil {
+		t.Error("Expected the cancelled caller to get an error")
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("Expected the analysis to keep running for the remaining caller")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if result := <-second; result == nil || result.Title != "Shared" {
+		t.Errorf("Expected the remaining caller to get the result, got %+v", result)
+	}
+}
+
+func TestAnalyzeCached_LastCallerLeavingCancelsAnalysis(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages, hits, _, cancelled := newBlockingPageServer()
+	defer pages.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		analyzeCached(ctx, pages.URL+"/abandoned", analyzer.AnalyzeOptions{SkipLinkCheck: true}, false, false)
+		close(done)
+	}()
+	waitFor(t, "the fetch to start", func() bool { return hits.Load() == 1 })
+	cancel()
+	<-done
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the abandoned analysis to be cancelled")
+	}
+}
@@ -1,11 +1,14 @@
 package server
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"fmt"
 	"html/template"
 	"net/http"
-	"time"
 	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/metrics"
+	"web-analyzer/internal/report"
 )
 
 var (
@@ -29,53 +32,107 @@ func ShowForm(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleAnalyzeJSON processes the URL and returns JSON
+// HandleAnalyzeJSON processes the URL and returns JSON, or an export format
+// (CSV, Markdown, HTML report or JUnit XML) selected by ?format= or Accept
 func HandleAnalyzeJSON(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	pageURL := r.FormValue("url")
-	if pageURL == "" {
-		http.Error(w, "URL is required", http.StatusBadRequest)
+	pageURL, opts, err := parseAnalyzeRequest(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-
-	if cached, ok := analyzer.GetFromCache(pageURL); ok {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(cached)
+	if opts, err = restrictRendering(r.Context(), opts); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Start analysis timer
-	start := time.Now()
+	// The export format comes from ?format= or the Accept header, JSON by default
+	format, err := report.Negotiate(r.Header.Get("Accept"), r.FormValue("format"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	// Run analysis
-	result, err := analyzer.AnalyzePage(pageURL)
+	result, err := analyzeCached(r.Context(), pageURL, opts, cacheBypass(r), recheckLinks(r))
 	if err != nil {
-		http.Error(w, "Failed to analyze: "+err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
-	result.AnalysisDuration = time.Since(start)
 
-	// Link classification
-	config := analyzer.LinkCheckerConfig{
-		MaxConcurrency: 10,
-		Timeout:        5 * time.Second,
+	// Render into a buffer so an export failure can still be reported as an error
+	var buf bytes.Buffer
+	if err := report.Write(&buf, format, result); err != nil {
+		http.Error(w, "Failed to encode "+string(format)+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	if format != report.FormatJSON && format != report.FormatHTML {
+		w.Header().Set("Content-Disposition", `attachment; filename="analysis.`+format.Extension()+`"`)
 	}
-	result.AccessibleLinks, result.InaccessibleLinks = analyzer.ClassifyLinksConcurrently(
-		append(result.InternalLinks, result.ExternalLinks...), config,
-	)
+	w.Write(buf.Bytes())
+}
+
+// analyzeCached serves pageURL from the shared cache or runs a fresh analysis,
+// storing complete results. With refresh set the cached result is ignored but
+// replaced. An expired result is revalidated with a conditional request first,
+// rechecking its links when recheckLinks is set. Concurrent requests for the
+// same page and options share one analysis. The analysis is bounded by
+// opts.MaxDuration, which never exceeds constants.AnalysisTimeout.
+func analyzeCached(ctx context.Context, pageURL string, opts analyzer.AnalyzeOptions, refresh, recheckLinks bool) (*analyzer.Result, error) {
+	// A refresh promises fresh link outcomes too
+	opts.SkipLinkCache = refresh
 
-	// Store in cache
-	analyzer.StoreInCache(pageURL, result)
+	// Authenticated analyses are never cached or shared; a client disconnect
+	// cancels outstanding work
+	if opts.Credentials != nil {
+		return analyzer.Analyze(ctx, pageURL, opts)
+	}
 
-	// Return JSON response
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		http.Error(w, "Failed to encode JSON: "+err.Error(), http.StatusInternalServerError)
+	// Results depend on the options, so they are part of the cache key
+	cacheKey := analyzer.CacheKey(pageURL, opts)
+	if !refresh {
+		if cached, ok := analyzer.GetFromCache(cacheKey); ok {
+			metrics.CacheHits.Inc()
+			return cached, nil
+		}
 	}
+
+	flightKey := fmt.Sprintf("%s|refresh=%t|recheck=%t", cacheKey, refresh, recheckLinks)
+	return analyses.do(ctx, flightKey, func(ctx context.Context) (*analyzer.Result, error) {
+		if !refresh {
+			// A flight that just finished may have filled the cache
+			if cached, ok := analyzer.GetFromCache(cacheKey); ok {
+				metrics.CacheHits.Inc()
+				return cached, nil
+			}
+			// An expired page that has not changed skips the full analysis
+			if stale, ok := analyzer.GetStaleFromCache(cacheKey); ok {
+				if result, ok := analyzer.Revalidate(ctx, stale, opts, recheckLinks); ok {
+					if !result.Partial {
+						analyzer.StoreInCache(cacheKey, result)
+					}
+					return result, nil
+				}
+			}
+			metrics.CacheMisses.Inc()
+		}
+
+		// The wall-time budget turns a slow page into a partial result
+		result, err := analyzer.Analyze(ctx, pageURL, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only complete results are cached
+		if !result.Partial {
+			analyzer.StoreInCache(cacheKey, result)
+		}
+		return result, nil
+	})
 }
 
 func ShowResultPage(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/logging"
+	"web-analyzer/internal/metrics"
+	"web-analyzer/internal/testutil"
+)
+
+func TestHandleAnalyzeJSON_ExportFormats(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer()
+	defer pages.Close()
+
+	cases := []struct {
+		query, accept, contentType string
+	}{
+		{"", "", "application/json"},
+		{"?format=csv", "", "text/csv; charset=utf-8"},
+		{"", "text/markdown", "text/markdown; charset=utf-8"},
+		{"?format=junit", "text/html", "application/xml; charset=utf-8"},
+	}
+	for _, c := range cases {
+		form := url.Values{"url": {pages.URL + "/export"}}
+		req := httptest.NewRequest(http.MethodPost, "/api/analyze"+c.query, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		rec := httptest.NewRecorder()
+
+		HandleAnalyzeJSON(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != c.contentType {
+			t.Errorf("%s %s: expected 200 %s, got %d %s", c.query, c.accept, c.contentType, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze?format=pdf", strings.NewReader("url="+pages.URL))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	HandleAnalyzeJSON(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown format, got %d", rec.Code)
+	}
+}
+
+func TestHandleAnalyzeJSON_PropagatesFailureStatus(t *testing.T) {
+	testutil.AllowLoopback(t)
+	notHTML := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+	}))
+	defer notHTML.Close()
+
+	for pageURL, want := range map[string]int{
+		"example.com":         http.StatusBadRequest,
+		notHTML.URL:           http.StatusUnprocessableEntity,
+		"http://127.0.0.1:1/": http.StatusBadGateway,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/analyze?refresh=true", strings.NewReader(url.Values{"url": {pageURL}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		HandleAnalyzeJSON(rec, req)
+		if rec.Code != want || strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("%s: expected a plain-text %d, got %d %q", pageURL, want, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestMetricsMiddleware_RecordsRoutePattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/thing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusTeapot)
+	})
+	mux.Handle("/metrics", metrics.Default.Handler())
+	handler := MetricsMiddleware(mux)
+
+	before := metrics.HTTPRequests.Value("/api/thing", "GET", "418")
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/thing?x=1", nil))
+	if got := metrics.HTTPRequests.Value("/api/thing", "GET", "418"); got != before+1 {
+		t.Errorf("Expected request to be counted under its route, got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`webanalyzer_http_requests_total{route="/api/thing",method="GET",code="418"}`,
+		`# TYPE webanalyzer_http_request_duration_seconds histogram`,
+		`# TYPE webanalyzer_cache_entries gauge`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected /metrics to contain %q", want)
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "upstream-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if seen != "upstream-42" || rec.Header().Get("X-Request-ID") != "upstream-42" {
+		t.Errorf("Expected client request ID to be propagated, got %q / %q", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "bad id\r\nInjected: yes")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if seen == "" || strings.Contains(seen, " ") || rec.Header().Get("X-Request-ID") != seen {
+		t.Errorf("Expected a generated request ID, got %q / %q", seen, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestHandleAnalyzeJSON_CacheBypass(t *testing.T) {
+	testutil.AllowLoopback(t)
+	var hits, linkChecks atomic.Int32
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/link" {
+			linkChecks.Add(1)
+			return
+		}
+		hits.Add(1)
+		w.Write([]byte(`<html><title>Cached</title><a href="/link">link</a></html>`))
+	}))
+	defer pages.Close()
+
+	analyze := func(pageURL, query string, header http.Header) {
+		req := httptest.NewRequest(http.MethodPost, "/api/analyze"+query, strings.NewReader(url.Values{"url": {pageURL}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		HandleAnalyzeJSON(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	analyze(pages.URL, "", nil)
+	analyze(pages.URL+"/", "", nil)
+	analyze(strings.ToUpper(pages.URL[:4])+pages.URL[4:], "", nil)
+	if hits.Load() != 1 {
+		t.Fatalf("Expected equivalent URLs to share one cache entry, got %d fetches", hits.Load())
+	}
+	analyze(pages.URL, "?refresh=true", nil)
+	analyze(pages.URL, "", http.Header{"Cache-Control": {"no-cache"}})
+	if hits.Load() != 3 {
+		t.Errorf("Expected refresh and no-cache to bypass the cache, got %d fetches", hits.Load())
+	}
+	if linkChecks.Load() != 3 {
+		t.Errorf("Expected refresh and no-cache to recheck links, got %d checks", linkChecks.Load())
+	}
+}
+
+func TestHandleAnalyzeJSON_RevalidatesExpiredEntry(t *testing.T) {
+	testutil.AllowLoopback(t)
+	analyzer.ConfigureCache(analyzer.CacheConfig{TTL: time.Millisecond})
+	defer analyzer.ConfigureCache(analyzer.CacheConfig{})
+
+	var fullFetches atomic.Int32
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullFetches.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<html><title>Validated</title></html>"))
+	}))
+	defer pages.Close()
+
+	analyze := func() *analyzer.Result {
+		req := httptest.NewRequest(http.MethodPost, "/api/analyze", strings.NewReader(url.Values{"url": {pages.URL}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		HandleAnalyzeJSON(rec, req)
+		var result analyzer.Result
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("Decoding result failed: %v", err)
+		}
+		return &result
+	}
+
+	if first := analyze(); first.Revalidated {
+		t.Error("Expected a fresh analysis first")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if second := analyze(); !second.Revalidated || second.Title != "Validated" {
+		t.Errorf("Expected the expired entry to be revalidated, got %+v", second)
+	}
+	if fullFetches.Load() != 1 {
+		t.Errorf("Expected one full fetch, got %d", fullFetches.Load())
+	}
+}
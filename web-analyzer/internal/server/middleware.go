@@ -1,45 +1,97 @@
 package server
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
-)
 
-var (
-	visitors = make(map[string]time.Time)
-	mu       sync.Mutex
-	limit    = time.Minute // 1 request per minute
+	"web-analyzer/internal/logging"
+	"web-analyzer/internal/metrics"
+	"web-analyzer/pkg/errors"
 )
 
-func RateLimit(next http.Handler) http.Handler {
+// RequestIDMiddleware propagates the client's X-Request-ID, or generates one,
+// into the request context and echoes it in the response headers.
+func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
-		mu.Lock()
-		last, seen := visitors[ip]
-		if seen && time.Since(last) < limit {
-			mu.Unlock()
-			http.Error(w, "Rate limit exceeded. Try again later.", http.StatusTooManyRequests)
-			return
+		id := r.Header.Get(logging.RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
 		}
-		visitors[ip] = time.Now()
-		mu.Unlock()
-
-		next.ServeHTTP(w, r)
+		w.Header().Set(logging.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
 	})
 }
 
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		// Wrap the ResponseWriter to capture status code if needed
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
+		rec := &statusRecorder{ResponseWriter: w}
+		slog.DebugContext(r.Context(), "request started", "method", r.Method, "path", r.URL.Path)
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		slog.InfoContext(r.Context(), "request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// statusRecorder captures the response status for logging and metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed batch output.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// MetricsMiddleware counts requests and records their latency by route,
+// method and status code. It must wrap the ServeMux so the matched route
+// pattern is known once the request has been served.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rec, r)
 
-		duration := time.Since(start)
-		log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, duration)
+		// Route patterns keep label cardinality bounded, unlike raw paths
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		code := strconv.Itoa(rec.status)
+		metrics.HTTPRequests.Inc(route, r.Method, code)
+		metrics.HTTPRequestDuration.ObserveSince(start, route, r.Method, code)
 	})
 }
 
@@ -47,8 +99,8 @@ func RecoverMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				log.Printf("Recovered from panic: %v", rec)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				slog.ErrorContext(r.Context(), "recovered from panic", "panic", rec, "path", r.URL.Path)
+				writeError(w, r, &errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: "Internal Server Error"})
 			}
 		}()
 		next(w, r)
@@ -67,7 +119,8 @@ func ErrorHandler(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				slog.ErrorContext(r.Context(), "handler panicked", "panic", err, "path", r.URL.Path)
+				writeError(w, r, &errors.HTTPError{StatusCode: http.StatusInternalServerError, Message: "Internal Server Error"})
 			}
 		}()
 		next(w, r)
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web-analyzer/internal/metrics"
+	"web-analyzer/pkg/errors"
+)
+
+// RateLimitConfig configures one token-bucket limiter.
+type RateLimitConfig struct {
+	Route string  // label used in metrics and logs
+	Rate  float64 // tokens added per second
+	Burst int     // bucket size: requests allowed at once after being idle
+
+	// TrustedProxies are the load balancers whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Requests from anywhere else are keyed
+	// by their connection address.
+	TrustedProxies []netip.Prefix
+
+	// IdleTTL is how long an unused bucket is kept; 0 means the time it takes
+	// to refill, but at least a minute.
+	IdleTTL time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits requests per client IP with a token bucket and evicts
+// idle clients in the background. Call Close to stop the eviction loop.
+type RateLimiter struct {
+	cfg RateLimitConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRateLimiter starts a limiter for cfg.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = time.Minute
+		if refill := time.Duration(float64(cfg.Burst) / cfg.Rate * float64(time.Second)); refill > cfg.IdleTTL {
+			cfg.IdleTTL = refill
+		}
+	}
+	l := &RateLimiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+		stop:    make(chan struct{}),
+	}
+	go l.evictLoop()
+	return l
+}
+
+// Close stops background eviction.
+func (l *RateLimiter) Close() {
+	l.closeOnce.Do(func() { close(l.stop) })
+}
+
+func (l *RateLimiter) evictLoop() {
+	ticker := time.NewTicker(l.cfg.IdleTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.evict()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// evict drops buckets unused for IdleTTL; they would be full again anyway.
+func (l *RateLimiter) evict() {
+	cutoff := l.now().Add(-l.cfg.IdleTTL)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if b.last.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of tracked clients.
+func (l *RateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// allow takes a token for key. It returns whether the request may proceed,
+// the whole tokens left, and how long until the next token is available.
+func (l *RateLimiter) allow(key string) (bool, int, time.Duration) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.cfg.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.cfg.Burst), b.tokens+now.Sub(b.last).Seconds()*l.cfg.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.cfg.Rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), time.Duration((float64(l.cfg.Burst) - b.tokens) / l.cfg.Rate * float64(time.Second))
+}
+
+// Middleware rejects requests over the limit with 429 and reports the quota
+// in RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+// Requests authenticated with an API key are limited by their key instead.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := APIKeyID(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if l.admit(w, r, ClientIP(r, l.cfg.TrustedProxies)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// admit takes a token for key and sets the RateLimit-* headers. When the
+// bucket is empty it writes a 429 with Retry-After and returns false.
+func (l *RateLimiter) admit(w http.ResponseWriter, r *http.Request, key string) bool {
+	ok, remaining, wait := l.allow(key)
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(l.cfg.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(wait)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", l.cfg.Burst, ceilSeconds(time.Duration(float64(l.cfg.Burst)/l.cfg.Rate*float64(time.Second)))))
+	if !ok {
+		metrics.RateLimitRejections.Inc(l.cfg.Route)
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusTooManyRequests, Message: "Rate limit exceeded. Try again later."})
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientIP returns the address requests are limited by. Forwarding headers
+// are only honoured when the connection comes from a trusted proxy; the
+// client is then the right-most X-Forwarded-For entry that is not itself a
+// trusted proxy.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	remote = remote.Unmap()
+	if !isTrustedProxy(remote, trusted) {
+		return remote.String()
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			if addr = addr.Unmap(); !isTrustedProxy(addr, trusted) {
+				return addr.String()
+			}
+		}
+	}
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+	return remote.String()
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies parses a comma-separated list of IPs and CIDRs.
+func ParseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ParseRateLimit parses "N/unit" or "N/unit,burst" where unit is s, m or h,
+// e.g. "10/m,5" for ten requests a minute with bursts of five. Without a
+// burst, the bucket holds one unit's worth of requests.
+func ParseRateLimit(spec string) (rate float64, burst int, err error) {
+	spec = strings.TrimSpace(spec)
+	ratePart, burstPart, hasBurst := strings.Cut(spec, ",")
+	count, unit, ok := strings.Cut(strings.TrimSpace(ratePart), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: expected N/unit[,burst]", spec)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: count must be a positive number", spec)
+	}
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s", "sec", "second":
+		per = time.Second
+	case "m", "min", "minute":
+		per = time.Minute
+	case "h", "hour":
+		per = time.Hour
+	default:
+		return 0, 0, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", spec)
+	}
+	rate = n / per.Seconds()
+	burst = int(math.Max(1, n))
+	if hasBurst {
+		if burst, err = strconv.Atoi(strings.TrimSpace(burstPart)); err != nil || burst < 1 {
+			return 0, 0, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", spec)
+		}
+	}
+	return rate, burst, nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(t *testing.T, cfg RateLimitConfig) (*RateLimiter, *time.Time) {
+	t.Helper()
+	l := NewRateLimiter(cfg)
+	t.Cleanup(l.Close)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestRateLimiter_BurstThenReject(t *testing.T) {
+	l, now := newTestLimiter(t, RateLimitConfig{Route: "/api/analyze", Rate: 1, Burst: 2})
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/analyze", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rec := do()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("Request %d: expected RateLimit-Remaining %s, got %s", i, wantRemaining, got)
+		}
+	}
+
+	rec := do()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after the burst, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected RateLimit-Limit 2, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=2" {
+		t.Errorf("Expected RateLimit-Policy 2;w=2, got %q", got)
+	}
+
+	*now = now.Add(time.Second)
+	if rec := do(); rec.Code != http.StatusOK {
+		t.Errorf("Expected a refilled token after one second, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiter_EvictsIdleClients(t *testing.T) {
+	l, now := newTestLimiter(t, RateLimitConfig{Rate: 1, Burst: 1, IdleTTL: time.Minute})
+	l.allow("a")
+	*now = now.Add(30 * time.Second)
+	l.allow("b")
+
+	*now = now.Add(45 * time.Second)
+	l.evict()
+	if l.Len() != 1 {
+		t.Fatalf("Expected only the recent client to remain, got %d", l.Len())
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("Expected client b to be kept")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{"untrusted remote ignores headers", "203.0.113.7:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"trusted proxy uses forwarded client", "10.1.2.3:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"skips trusted hops from the right", "10.1.2.3:1234", []string{"6.6.6.6, 198.51.100.1, 192.0.2.1"}, "", "198.51.100.1"},
+		{"joins repeated headers", "10.1.2.3:1234", []string{"6.6.6.6", "198.51.100.9"}, "", "198.51.100.9"},
+		{"falls back to X-Real-IP", "10.1.2.3:1234", nil, "198.51.100.2", "198.51.100.2"},
+		{"unmaps IPv4-in-IPv6", "[::ffff:203.0.113.7]:1234", nil, "", "203.0.113.7"},
+		{"trusted proxy without headers", "192.0.2.1:1234", nil, "", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(req, trusted); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies("10.1.2.3/8, ::1,")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if _, err := ParseTrustedProxies("not-an-ip"); err == nil {
+		t.Error("Expected an error for an invalid entry")
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantRate  float64
+		wantBurst int
+		wantErr   bool
+	}{
+		{"10/m,5", 10.0 / 60, 5, false},
+		{"2/s", 2, 2, false},
+		{"0.5/s", 0.5, 1, false},
+		{"100/h", 100.0 / 3600, 100, false},
+		{"10", 0, 0, true},
+		{"10/d", 0, 0, true},
+		{"-1/s", 0, 0, true},
+		{"10/m,0", 0, 0, true},
+	}
+	for _, tt := range tests {
+		rate, burst, err := ParseRateLimit(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error state: %v", tt.spec, err)
+			continue
+		}
+		if rate != tt.wantRate || burst != tt.wantBurst {
+			t.Errorf("%q: expected %v/%d, got %v/%d", tt.spec, tt.wantRate, tt.wantBurst, rate, burst)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/pkg/errors"
+)
+
+// analyzeRequest is the JSON body accepted by the analyze endpoint.
+type analyzeRequest struct {
+	URL     string              `json:"url"`
+	Options *analyzeOptionsJSON `json:"options,omitempty"`
+}
+
+// analyzeOptionsJSON is the wire form of analyzer.AnalyzeOptions, with
+// timeouts in milliseconds.
+type analyzeOptionsJSON struct {
+	UserAgent       string            `json:"userAgent,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	FetchTimeoutMs  int64             `json:"fetchTimeoutMs,omitempty"`
+	RenderTimeoutMs int64             `json:"renderTimeoutMs,omitempty"`
+	LinkTimeoutMs   int64             `json:"linkTimeoutMs,omitempty"`
+	LinkConcurrency int               `json:"linkConcurrency,omitempty"`
+	CheckLinks      *bool             `json:"checkLinks,omitempty"`
+	RenderMode      string            `json:"renderMode,omitempty"`
+	Credentials     *credentialsJSON  `json:"credentials,omitempty"`
+	Proxy           string            `json:"proxy,omitempty"`
+	MaxLinks        int               `json:"maxLinks,omitempty"`
+	MaxDurationMs   int64             `json:"maxDurationMs,omitempty"`
+	MaxDOMNodes     int               `json:"maxDomNodes,omitempty"`
+}
+
+// credentialsJSON authenticates the analysis against the analyzed site.
+type credentialsJSON struct {
+	Cookies []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"cookies,omitempty"`
+	Cookie      string `json:"cookie,omitempty"`
+	BearerToken string `json:"bearerToken,omitempty"`
+	BasicAuth   *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"basicAuth,omitempty"`
+}
+
+func (c *credentialsJSON) toCredentials() *helpers.Credentials {
+	if c == nil {
+		return nil
+	}
+	creds := &helpers.Credentials{CookieHeader: c.Cookie, BearerToken: c.BearerToken}
+	for _, cookie := range c.Cookies {
+		creds.Cookies = append(creds.Cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if c.BasicAuth != nil {
+		creds.Username, creds.Password = c.BasicAuth.Username, c.BasicAuth.Password
+	}
+	return creds
+}
+
+func (o *analyzeOptionsJSON) toOptions() analyzer.AnalyzeOptions {
+	if o == nil {
+		return analyzer.AnalyzeOptions{}
+	}
+	return analyzer.AnalyzeOptions{
+		UserAgent:       o.UserAgent,
+		Headers:         o.Headers,
+		FetchTimeout:    time.Duration(o.FetchTimeoutMs) * time.Millisecond,
+		RenderTimeout:   time.Duration(o.RenderTimeoutMs) * time.Millisecond,
+		LinkTimeout:     time.Duration(o.LinkTimeoutMs) * time.Millisecond,
+		LinkConcurrency: o.LinkConcurrency,
+		SkipLinkCheck:   o.CheckLinks != nil && !*o.CheckLinks,
+		RenderMode:      analyzer.RenderMode(o.RenderMode),
+		Credentials:     o.Credentials.toCredentials(),
+		Proxy:           o.Proxy,
+		MaxLinks:        o.MaxLinks,
+		MaxDuration:     time.Duration(o.MaxDurationMs) * time.Millisecond,
+		MaxDOMNodes:     o.MaxDOMNodes,
+	}
+}
+
+// decodeOptionValues sets the string, integer and boolean fields of the
+// options struct dst from the values named by their JSON tags. Headers and
+// credentials are only accepted in JSON bodies; other names, such as
+// refresh, are left to their handlers.
+func decodeOptionValues(values url.Values, dst any) error {
+	v := reflect.ValueOf(dst).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		raw := values.Get(name)
+		if name == "" || raw == "" {
+			continue
+		}
+		field := v.Field(i)
+		switch kind := field.Kind(); {
+		case kind == reflect.String:
+			field.SetString(raw)
+		case kind == reflect.Int || kind == reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return errors.New(errors.CodeInvalidOptions, err, "option %s must be an integer", name)
+			}
+			field.SetInt(n)
+		case kind == reflect.Pointer && field.Type().Elem().Kind() == reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return errors.New(errors.CodeInvalidOptions, err, "option %s must be true or false", name)
+			}
+			field.Set(reflect.ValueOf(&b))
+		default:
+			return errors.New(errors.CodeInvalidOptions, nil, "option %s is only accepted in a JSON body", name)
+		}
+	}
+	return nil
+}
+
+// parseAnalyzeRequest reads the target URL and options from a JSON body, or
+// just the URL from a form submission.
+func parseAnalyzeRequest(r *http.Request) (string, analyzer.AnalyzeOptions, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		pageURL := r.FormValue("url")
+		if pageURL == "" {
+			return "", analyzer.AnalyzeOptions{}, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "URL is required"}
+		}
+		return withURLCredentials(pageURL, analyzer.AnalyzeOptions{})
+	}
+
+	var req analyzeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)).Decode(&req); err != nil {
+		return "", analyzer.AnalyzeOptions{}, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid JSON body: " + err.Error()}
+	}
+	if req.URL == "" {
+		return "", analyzer.AnalyzeOptions{}, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "URL is required"}
+	}
+	opts := req.Options.toOptions()
+	if err := opts.Validate(); err != nil {
+		return "", analyzer.AnalyzeOptions{}, err
+	}
+	return withURLCredentials(req.URL, opts)
+}
+
+// withURLCredentials moves user:password out of the URL and into the options
+// so it cannot end up in a cache key or the result. A URL with user:password
+// and explicit credentials is ambiguous and rejected.
+func withURLCredentials(pageURL string, opts analyzer.AnalyzeOptions) (string, analyzer.AnalyzeOptions, error) {
+	pageURL, creds := helpers.StripURLCredentials(pageURL)
+	if creds != nil {
+		if opts.Credentials != nil {
+			return "", analyzer.AnalyzeOptions{}, errors.New(errors.CodeInvalidOptions, nil, "credentials given both in the URL and in the options")
+		}
+		opts.Credentials = creds
+	}
+	return pageURL, opts, nil
+}
+
+// cacheBypass reports whether the caller asked for a fresh analysis with
+// ?refresh=true or Cache-Control: no-cache.
+func cacheBypass(r *http.Request) bool {
+	if refresh, err := strconv.ParseBool(r.FormValue("refresh")); err == nil && refresh {
+		return true
+	}
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+// recheckLinks reports whether ?recheckLinks=true asked for the links of a
+// revalidated page to be checked again.
+func recheckLinks(r *http.Request) bool {
+	recheck, _ := strconv.ParseBool(r.FormValue("recheckLinks"))
+	return recheck
+}
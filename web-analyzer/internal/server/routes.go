@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+
+	"web-analyzer/internal/metrics"
+	"web-analyzer/pkg/api"
+)
+
+// RoutesConfig holds what the routes are wired with.
+type RoutesConfig struct {
+	AnalyzeLimiter *RateLimiter
+	BatchLimiter   *RateLimiter
+	Keys           *APIKeyStore // nil = the API is open
+	AdminToken     string       // "" = no admin endpoints
+}
+
+// NewMux registers every route with its middleware chain and wraps the result
+// in the request ID, logging and metrics middleware.
+func NewMux(cfg RoutesConfig) http.Handler {
+	// API keys are required once a key store is configured
+	requireKey := func(h http.Handler) http.Handler { return h }
+	if cfg.Keys != nil {
+		requireKey = cfg.Keys.Middleware
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", ShowForm)
+	mux.Handle("/api/analyze", Chain(
+		http.HandlerFunc(ErrorHandler(HandleAnalyzeJSON)),
+		cfg.AnalyzeLimiter.Middleware,
+		requireKey,
+	))
+	mux.Handle("/api/batch", Chain(
+		http.HandlerFunc(ErrorHandler(HandleBatch)),
+		RequireFeature(FeatureBatch),
+		cfg.BatchLimiter.Middleware,
+		requireKey,
+	))
+	mux.Handle(api.Version+"/analyze", Chain(
+		http.HandlerFunc(ErrorHandler(HandleAnalyzeV1)),
+		cfg.AnalyzeLimiter.Middleware,
+		requireKey,
+	))
+	mux.Handle(api.Version+"/batch", Chain(
+		http.HandlerFunc(ErrorHandler(HandleBatchV1)),
+		RequireFeature(FeatureBatch),
+		cfg.BatchLimiter.Middleware,
+		requireKey,
+	))
+	mux.HandleFunc(api.Version+"/openapi.json", HandleOpenAPI)
+	mux.HandleFunc(api.Version+"/", HandleNotFoundV1)
+	if cfg.Keys != nil {
+		mux.Handle("/api/usage", cfg.Keys.Authenticate(http.HandlerFunc(cfg.Keys.HandleUsage)))
+		mux.Handle(api.Version+"/usage", cfg.Keys.Authenticate(http.HandlerFunc(cfg.Keys.HandleUsage)))
+		if cfg.AdminToken != "" {
+			adminAuth := AdminAuth(cfg.AdminToken)
+			mux.Handle("/admin/keys", adminAuth(http.HandlerFunc(cfg.Keys.HandleAdminKeys)))
+			mux.Handle("/admin/keys/{id}", adminAuth(http.HandlerFunc(cfg.Keys.HandleAdminKey)))
+		}
+	}
+	mux.HandleFunc("/result", ShowResultPage)
+	mux.Handle("/metrics", metrics.Default.Handler())
+
+	return Chain(mux,
+		MetricsMiddleware,
+		LoggingMiddleware,
+		RequestIDMiddleware,
+	)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/logging"
+	"web-analyzer/pkg/api"
+	"web-analyzer/pkg/embed"
+	"web-analyzer/pkg/errors"
+)
+
+// writeError writes err with its HTTPError status, or 500 for any other
+// error: as the JSON error envelope on /api/v1 paths and as plain text on the
+// legacy endpoints.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(r.Context(), err)
+	if !isV1(r) {
+		http.Error(w, apiErr.Message, apiErr.Status)
+		return
+	}
+	writeJSON(w, apiErr.Status, api.ErrorResponse{Error: apiErr})
+}
+
+// toAPIError describes err for the v1 envelope and batch error lines.
+func toAPIError(ctx context.Context, err error) api.Error {
+	status, code := http.StatusInternalServerError, errors.CodeInternal
+	var httpErr *errors.HTTPError
+	if stderrors.As(err, &httpErr) {
+		status, code = httpErr.StatusCode, httpErr.ErrorCode()
+	}
+	return api.Error{
+		Code:      code,
+		Message:   err.Error(),
+		Status:    status,
+		RequestID: logging.RequestID(ctx),
+	}
+}
+
+func isV1(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, api.Version+"/")
+}
+
+// HandleAnalyzeV1 analyzes the page in an api.AnalyzeRequest body and returns
+// an api.Result. Failures keep the status of the underlying error.
+func HandleAnalyzeV1(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusMethodNotAllowed, Message: "Only POST allowed"})
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json"})
+		return
+	}
+
+	var req api.AnalyzeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid JSON body: " + err.Error()})
+		return
+	}
+	if req.URL == "" {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "URL is required"})
+		return
+	}
+	opts := optionsFromV1(req.Options)
+	if err := opts.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	pageURL, opts, err := withURLCredentials(req.URL, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if opts, err = restrictRendering(r.Context(), opts); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := analyzeCached(r.Context(), pageURL, opts, cacheBypass(r), recheckLinks(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result.ToAPI())
+}
+
+// HandleBatchV1 is HandleBatch with api.BatchRequest bodies and api.BatchLine
+// output; failed URLs carry an error envelope instead of a plain message.
+func HandleBatchV1(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusMethodNotAllowed, Message: "Only POST allowed"})
+		return
+	}
+
+	urls, opts, err := parseBatchRequest(r, decodeBatchV1, decodeBatchValuesV1)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if opts, err = restrictRendering(r.Context(), opts); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !consumeBatchQuota(w, r, len(urls)) {
+		return
+	}
+
+	serveBatch(w, r, urls, opts, func(idx int, result *analyzer.Result, err error) any {
+		line := api.BatchLine{Index: idx, URL: urls[idx]}
+		if err != nil {
+			apiErr := toAPIError(r.Context(), err)
+			line.Error = &apiErr
+		} else {
+			line.Result = result.ToAPI()
+		}
+		return line
+	})
+}
+
+// decodeBatchV1 reads an api.BatchRequest body.
+func decodeBatchV1(body io.Reader) ([]string, analyzer.AnalyzeOptions, error) {
+	var req api.BatchRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, analyzer.AnalyzeOptions{}, &errors.HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid JSON body: " + err.Error()}
+	}
+	return trimURLs(req.URLs), optionsFromV1(req.Options), nil
+}
+
+// decodeBatchValuesV1 reads api.AnalyzeOptions fields from form or query
+// values.
+func decodeBatchValuesV1(values url.Values) (analyzer.AnalyzeOptions, error) {
+	var opts api.AnalyzeOptions
+	if err := decodeOptionValues(values, &opts); err != nil {
+		return analyzer.AnalyzeOptions{}, err
+	}
+	return optionsFromV1(&opts), nil
+}
+
+// HandleOpenAPI serves the OpenAPI document describing the service.
+func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, r, &errors.HTTPError{StatusCode: http.StatusMethodNotAllowed, Message: "Only GET allowed"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(embed.OpenAPISpec)
+}
+
+// HandleNotFoundV1 answers unknown /api/v1 paths with the error envelope.
+func HandleNotFoundV1(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &errors.HTTPError{StatusCode: http.StatusNotFound, Message: "No such endpoint: " + r.URL.Path})
+}
+
+func optionsFromV1(o *api.AnalyzeOptions) analyzer.AnalyzeOptions {
+	if o == nil {
+		return analyzer.AnalyzeOptions{}
+	}
+	opts := &analyzeOptionsJSON{
+		UserAgent:       o.UserAgent,
+		Headers:         o.Headers,
+		FetchTimeoutMs:  o.FetchTimeoutMs,
+		RenderTimeoutMs: o.RenderTimeoutMs,
+		LinkTimeoutMs:   o.LinkTimeoutMs,
+		LinkConcurrency: o.LinkConcurrency,
+		CheckLinks:      o.CheckLinks,
+		RenderMode:      o.RenderMode,
+		Proxy:           o.Proxy,
+		MaxLinks:        o.MaxLinks,
+		MaxDurationMs:   o.MaxDurationMs,
+		MaxDOMNodes:     o.MaxDOMNodes,
+	}
+	if c := o.Credentials; c != nil {
+		opts.Credentials = &credentialsJSON{Cookie: c.Cookie, BearerToken: c.BearerToken}
+		for _, cookie := range c.Cookies {
+			opts.Credentials.Cookies = append(opts.Credentials.Cookies, struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			}{cookie.Name, cookie.Value})
+		}
+		if c.BasicAuth != nil {
+			opts.Credentials.BasicAuth = &struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+			}{c.BasicAuth.Username, c.BasicAuth.Password}
+		}
+	}
+	return opts.toOptions()
+}
+
+func usageToV1(info APIKeyInfo) api.Usage {
+	features := make([]string, 0, len(info.Features))
+	for _, f := range info.Features {
+		features = append(features, string(f))
+	}
+	return api.Usage{
+		ID:         info.ID,
+		RateLimit:  info.RateLimit,
+		DailyQuota: info.DailyQuota,
+		Features:   features,
+		Day:        info.Usage.Day,
+		Today:      info.Usage.Today,
+		Total:      info.Usage.Total,
+		Rejected:   info.Usage.Rejected,
+		LastUsed:   info.Usage.LastUsed,
+	}
+}
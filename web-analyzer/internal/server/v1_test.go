@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-analyzer/internal/logging"
+	"web-analyzer/internal/testutil"
+	"web-analyzer/pkg/api"
+	"web-analyzer/pkg/embed"
+)
+
+func postV1(h http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	RequestIDMiddleware(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeV1Error(t *testing.T, rec *httptest.ResponseRecorder) api.Error {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Expected a JSON error, got %q: %s", ct, rec.Body)
+	}
+	var resp api.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Invalid error envelope: %v", err)
+	}
+	return resp.Error
+}
+
+func TestHandleAnalyzeV1_SnakeCaseResult(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>V1</title><meta http-equiv="refresh" content="2; url=/next"></head><body><h1>Hi</h1></body></html>`))
+	}))
+	defer pages.Close()
+
+	rec := postV1(HandleAnalyzeV1, "/api/v1/analyze?refresh=true", `{"url":"`+pages.URL+`","options":{"check_links":false}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var raw map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+	for _, field := range []string{"page_url", "html_version", "internal_links", "analysis_duration_ms"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("Expected field %q in %s", field, rec.Body)
+		}
+	}
+	if links, ok := raw["internal_links"].([]any); !ok || len(links) != 0 {
+		t.Errorf("Expected empty links as [], got %v", raw["internal_links"])
+	}
+
+	var result api.Result
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Title != "V1" || len(result.Headings) != 1 || result.Headings[0].Text != "Hi" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(result.ClientRedirects) != 1 || result.ClientRedirects[0].DelayMs != 2000 {
+		t.Errorf("Expected the refresh delay in milliseconds, got %+v", result.ClientRedirects)
+	}
+}
+
+func TestHandleAnalyzeV1_ErrorEnvelope(t *testing.T) {
+	testutil.AllowLoopback(t)
+	notHTML := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	}))
+	defer notHTML.Close()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid JSON", `{`, http.StatusBadRequest, "bad_request"},
+		{"missing URL", `{}`, http.StatusBadRequest, "bad_request"},
+		{"invalid option", `{"url":"https://example.com","options":{"render_mode":"sometimes"}}`, http.StatusBadRequest, "invalid_options"},
+		{"invalid URL", `{"url":"example.com"}`, http.StatusBadRequest, "invalid_url"},
+		{"ambiguous credentials", `{"url":"https://u:p@example.com","options":{"credentials":{"bearer_token":"t"}}}`, http.StatusBadRequest, "invalid_options"},
+		{"not HTML", `{"url":"` + notHTML.URL + `","options":{"check_links":false}}`, http.StatusUnprocessableEntity, "unsupported_content"},
+		{"connection refused", `{"url":"http://127.0.0.1:1/","options":{"check_links":false}}`, http.StatusBadGateway, "connection_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postV1(HandleAnalyzeV1, "/api/v1/analyze?refresh=true", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			apiErr := decodeV1Error(t, rec)
+			if apiErr.Code != tt.wantCode || apiErr.Status != tt.wantStatus || apiErr.Message == "" {
+				t.Errorf("Unexpected error: %+v", apiErr)
+			}
+			if apiErr.RequestID == "" || apiErr.RequestID != rec.Header().Get(logging.RequestIDHeader) {
+				t.Errorf("Expected the request ID %q, got %q", rec.Header().Get(logging.RequestIDHeader), apiErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestWriteError_LegacyPathsStayPlainText(t *testing.T) {
+	for _, path := range []string{"/api/analyze", "/api/v1/analyze"} {
+		l, _ := newTestLimiter(t, RateLimitConfig{Rate: 1, Burst: 1})
+		h := l.Middleware(okHandler)
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		}
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: expected 429, got %d", path, rec.Code)
+		}
+		isJSON := rec.Header().Get("Content-Type") == "application/json"
+		if isJSON != strings.HasPrefix(path, api.Version) {
+			t.Errorf("%s: unexpected Content-Type %q", path, rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestHandleBatchV1_InlineErrorEnvelopes(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer()
+	defer pages.Close()
+
+	rec := postV1(HandleBatchV1, "/api/v1/batch", `{"urls":["`+pages.URL+`/a","://bad"],"options":{"check_links":false}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	lines := make(map[int]api.BatchLine)
+	scanner := bufio.NewScanner(bytes.NewReader(rec.Body.Bytes()))
+	for scanner.Scan() {
+		var line api.BatchLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines[line.Index] = line
+	}
+	if lines[0].Result == nil || lines[0].Result.Title != "Page /a" {
+		t.Errorf("Expected a result for the first URL, got %+v", lines[0])
+	}
+	if lines[1].Error == nil || lines[1].Error.Code != "invalid_url" || lines[1].Error.Status != http.StatusBadRequest {
+		t.Errorf("Expected an error envelope for the invalid URL, got %+v", lines[1])
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleOpenAPI(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected 200 JSON, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(embed.OpenAPISpec, &spec); err != nil {
+		t.Fatalf("Invalid OpenAPI document: %v", err)
+	}
+	for path, method := range map[string]string{
+		"/api/v1/analyze":      "post",
+		"/api/v1/batch":        "post",
+		"/api/v1/usage":        "get",
+		"/api/v1/openapi.json": "get",
+		"/admin/keys":          "post",
+		"/admin/keys/{id}":     "delete",
+		"/metrics":             "get",
+	} {
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("Expected %s %s in the OpenAPI document", strings.ToUpper(method), path)
+		}
+	}
+}
+
+func TestHandleNotFoundV1(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleNotFoundV1(rec, httptest.NewRequest(http.MethodGet, "/api/v1/nope", nil))
+	if rec.Code != http.StatusNotFound || decodeV1Error(t, rec).Code != "not_found" {
+		t.Errorf("Expected a not_found envelope, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,17 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"testing"
+
+	"web-analyzer/internal/helpers"
+)
+
+// AllowLoopback lets the test reach httptest servers, which listen on
+// loopback, through the SSRF guard. The guard is restored when the test ends.
+func AllowLoopback(t testing.TB) {
+	t.Helper()
+	original := helpers.OutboundGuard
+	helpers.OutboundGuard = helpers.NewSSRFGuard("127.0.0.1")
+	t.Cleanup(func() { helpers.OutboundGuard = original })
+}
@@ -0,0 +1,365 @@
+// Package analyzer analyzes web pages in-process, without running the
+// web-analyzer server:
+//
+//	a, err := analyzer.New(analyzer.Config{Cache: analyzer.CacheConfig{MaxEntries: 100}})
+//	...
+//	result, err := a.Analyze(ctx, "https://example.com", analyzer.Options{})
+//
+// Results use the /api/v1 schema from pkg/api. Errors are *errors.HTTPError
+// from pkg/errors, so
+//
+//	stderrors.Is(err, errors.ErrDNS)
+//
+// works the same as against the server. Each Analyzer holds its own
+// configuration, fetcher, renderer, egress, caches and metrics, and reads no
+// environment variables; several can be used side by side.
+package analyzer
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/constants"
+	"web-analyzer/internal/helpers"
+	"web-analyzer/internal/metrics"
+	"web-analyzer/pkg/api"
+	"web-analyzer/pkg/errors"
+)
+
+// RenderMode controls when a page is rendered in a headless browser.
+type RenderMode string
+
+const (
+	RenderAuto   RenderMode = "auto"   // only when bot protection is detected
+	RenderAlways RenderMode = "always" // always analyze the rendered DOM
+	RenderNever  RenderMode = "never"  // analyze the fetched HTML even when it is a challenge page
+)
+
+// Options tune one analysis. Zero values take the same defaults as the
+// server, so the zero Options is a normal, link-checking analysis.
+type Options struct {
+	UserAgent       string
+	Headers         map[string]string
+	FetchTimeout    time.Duration
+	RenderTimeout   time.Duration
+	LinkTimeout     time.Duration
+	LinkConcurrency int
+	SkipLinkCheck   bool
+	RenderMode      RenderMode
+	Proxy           string // http://, https:// or socks5:// egress for this analysis; "" = Config.Proxy
+
+	// Budgets; when one runs out the result is returned partial with the
+	// affected parts listed in Result.Skipped.
+	MaxLinks    int
+	MaxDuration time.Duration
+	MaxDOMNodes int
+
+	// Credentials are sent only to the page's origin. Authenticated results
+	// are never cached.
+	Credentials *Credentials
+
+	Refresh bool // ignore a cached result and cached link outcomes and analyze again
+}
+
+// Credentials authenticate requests to the analyzed site.
+type Credentials struct {
+	Cookies      []*http.Cookie
+	CookieHeader string // raw "name=value; name2=value2" header
+	BearerToken  string
+	Username     string // basic auth; a bearer token takes precedence
+	Password     string
+}
+
+// FetchRequest is what a Fetcher or Renderer is asked to retrieve.
+type FetchRequest struct {
+	URL         string
+	UserAgent   string
+	Headers     map[string]string
+	Timeout     time.Duration
+	Proxy       string       // "" = Config.Proxy
+	Credentials *Credentials // for URL's origin only; nil when unauthenticated
+}
+
+// Page is a fetched HTML page.
+type Page struct {
+	URL        string // after redirects; "" = the requested URL
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Truncated  bool // Body was cut at a size limit
+}
+
+// Fetcher retrieves pages. Errors that are not an *errors.HTTPError are
+// reported as fetch_failed.
+type Fetcher interface {
+	Fetch(ctx context.Context, req FetchRequest) (*Page, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher.
+type FetcherFunc func(ctx context.Context, req FetchRequest) (*Page, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, req FetchRequest) (*Page, error) { return f(ctx, req) }
+
+// Renderer returns the DOM of a page after its scripts ran. Errors that are
+// not an *errors.HTTPError are reported as render_unavailable.
+type Renderer interface {
+	Render(ctx context.Context, req FetchRequest) ([]byte, error)
+}
+
+// RendererFunc adapts a function to a Renderer.
+type RendererFunc func(ctx context.Context, req FetchRequest) ([]byte, error)
+
+func (f RendererFunc) Render(ctx context.Context, req FetchRequest) ([]byte, error) {
+	return f(ctx, req)
+}
+
+// CacheConfig sizes the result cache. MaxEntries 0 disables it.
+type CacheConfig struct {
+	MaxEntries int
+	TTL        time.Duration // 0 = the server default
+}
+
+// LinkCacheConfig sizes the link-status cache, which lets analyses of pages
+// on the same site skip rechecking shared links. MaxEntries 0 disables it.
+type LinkCacheConfig struct {
+	MaxEntries int
+	SuccessTTL time.Duration // 0 = the server default
+	FailureTTL time.Duration // 0 = the server default
+}
+
+// Config configures an Analyzer. The zero Config fetches over HTTP, renders
+// through the render server at http://localhost:3001 and caches nothing.
+type Config struct {
+	// Headings are the tags reported as headings; nil = the embedded
+	// default, h1 to h6.
+	Headings []string
+
+	// Fetcher replaces the built-in HTTP fetcher. Links are always checked
+	// over HTTP.
+	Fetcher Fetcher
+
+	// Renderer replaces the render server client.
+	Renderer Renderer
+	// RenderServer is the render server's base URL when Renderer is nil;
+	// "" = http://localhost:3001.
+	RenderServer string
+
+	// AllowedHosts are hostnames, IPs or CIDRs that the built-in fetcher,
+	// the link checker and the render server may reach although they are
+	// private or loopback addresses, which are refused otherwise.
+	AllowedHosts []string
+
+	// Proxy is the http://, https:// or socks5:// egress for fetches, link
+	// checks and renders; "" = direct connections.
+	Proxy string
+
+	// LinkClient sends the link checks' HEAD requests in place of a client
+	// that enforces AllowedHosts and Proxy. Options.Proxy does not apply to
+	// it; Options.LinkTimeout does.
+	LinkClient *http.Client
+
+	// MaxBodyBytes caps the fetched or rendered HTML that is analyzed;
+	// the rest is cut off. 0 = 5 MiB.
+	MaxBodyBytes int64
+	// ReadTimeout bounds the page fetch when Options.FetchTimeout is
+	// unset; 0 = 10s.
+	ReadTimeout time.Duration
+
+	Cache     CacheConfig
+	LinkCache LinkCacheConfig
+}
+
+// Analyzer analyzes pages with one Config. It is safe for concurrent use.
+type Analyzer struct {
+	pipeline analyzer.Pipeline
+	cache    *analyzer.ResultCache // nil = no result cache
+	metrics  *metrics.Registry
+}
+
+// New returns an Analyzer for cfg.
+func New(cfg Config) (*Analyzer, error) {
+	tags := &analyzer.TagConfig{}
+	for _, h := range cfg.Headings {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			tags.Headings = append(tags.Headings, h)
+		}
+	}
+	if cfg.Headings == nil {
+		embedded, err := analyzer.EmbeddedTagConfig()
+		if err != nil {
+			return nil, fmt.Errorf("analyzer: loading the default headings: %w", err)
+		}
+		tags = embedded
+	}
+
+	var proxy *url.URL
+	if cfg.Proxy != "" {
+		var err error
+		if proxy, err = helpers.ParseProxyURL(cfg.Proxy); err != nil {
+			return nil, fmt.Errorf("analyzer: %w", err)
+		}
+	}
+	if cfg.RenderServer == "" {
+		cfg.RenderServer = constants.DefaultRenderServer
+	}
+
+	registry := metrics.NewRegistry()
+	meters := metrics.NewAnalyzer(registry)
+	a := &Analyzer{
+		pipeline: analyzer.Pipeline{
+			Fetch:      helpers.TryStandardFetchContext,
+			Render:     renderServer(cfg.RenderServer),
+			TagConfig:  tags,
+			Egress:     helpers.NewEgress(helpers.NewSSRFGuard(cfg.AllowedHosts...), proxy),
+			LinkClient: cfg.LinkClient,
+			Metrics:    meters,
+			Limits:     &helpers.FetchLimits{MaxBodyBytes: cfg.MaxBodyBytes, ReadTimeout: cfg.ReadTimeout},
+		},
+		metrics: registry,
+	}
+	if cfg.Fetcher != nil {
+		a.pipeline.Fetch = fetchWith(cfg.Fetcher)
+	}
+	if cfg.Renderer != nil {
+		a.pipeline.Render = renderWith(cfg.Renderer)
+	}
+	if cfg.Cache.MaxEntries > 0 {
+		a.cache = analyzer.NewResultCache(analyzer.CacheConfig{MaxEntries: cfg.Cache.MaxEntries, TTL: cfg.Cache.TTL, Entries: meters.CacheEntries})
+	}
+	if c := cfg.LinkCache; c.MaxEntries > 0 {
+		a.pipeline.LinkCache = analyzer.NewLinkStatusCache(analyzer.LinkCacheConfig{
+			MaxEntries: c.MaxEntries,
+			SuccessTTL: c.SuccessTTL,
+			FailureTTL: c.FailureTTL,
+		})
+	}
+	return a, nil
+}
+
+// Analyze fetches pageURL, renders it according to opts.RenderMode, parses
+// it and checks its links. A complete result is served from the cache until
+// it expires. If ctx ends or a budget runs out during link checking, the
+// partial result is returned with Partial set.
+func (a *Analyzer) Analyze(ctx context.Context, pageURL string, opts Options) (*api.Result, error) {
+	o := opts.internal()
+	o.SkipLinkCache = opts.Refresh
+	// user:password in the URL is used as basic auth, as on the server
+	pageURL, urlCreds := helpers.StripURLCredentials(pageURL)
+	if urlCreds != nil && o.Credentials == nil {
+		o.Credentials = urlCreds
+	}
+
+	if a.cache == nil || o.Credentials != nil {
+		result, err := a.pipeline.Analyze(ctx, pageURL, o)
+		if err != nil {
+			return nil, err
+		}
+		return result.ToAPI(), nil
+	}
+
+	key := a.cache.Key(pageURL, o)
+	if !opts.Refresh {
+		if cached, ok := a.cache.Get(key); ok {
+			return cached.ToAPI(), nil
+		}
+	}
+	result, err := a.pipeline.Analyze(ctx, pageURL, o)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Partial {
+		a.cache.Store(key, result)
+	}
+	return result.ToAPI(), nil
+}
+
+// WriteMetrics writes the Analyzer's metrics, such as phase durations and
+// link-check outcomes, in the Prometheus text format.
+func (a *Analyzer) WriteMetrics(w io.Writer) error {
+	return a.metrics.WriteText(w)
+}
+
+func (o Options) internal() analyzer.AnalyzeOptions {
+	return analyzer.AnalyzeOptions{
+		UserAgent:       o.UserAgent,
+		Headers:         o.Headers,
+		FetchTimeout:    o.FetchTimeout,
+		RenderTimeout:   o.RenderTimeout,
+		LinkTimeout:     o.LinkTimeout,
+		LinkConcurrency: o.LinkConcurrency,
+		SkipLinkCheck:   o.SkipLinkCheck,
+		RenderMode:      analyzer.RenderMode(o.RenderMode),
+		Proxy:           o.Proxy,
+		MaxLinks:        o.MaxLinks,
+		MaxDuration:     o.MaxDuration,
+		MaxDOMNodes:     o.MaxDOMNodes,
+		Credentials:     (*helpers.Credentials)(o.Credentials),
+	}
+}
+
+func fetchRequest(url string, o helpers.FetchOptions) FetchRequest {
+	return FetchRequest{
+		URL:         url,
+		UserAgent:   o.UserAgent,
+		Headers:     o.Headers,
+		Timeout:     o.Timeout,
+		Proxy:       o.Proxy,
+		Credentials: (*Credentials)(o.Credentials),
+	}
+}
+
+// fetchWith adapts f to the internal fetch hook, detecting bot protection the
+// way the built-in fetcher does.
+func fetchWith(f Fetcher) func(context.Context, string, helpers.FetchOptions) (*helpers.FetchResult, error) {
+	return func(ctx context.Context, url string, o helpers.FetchOptions) (*helpers.FetchResult, error) {
+		page, err := f.Fetch(ctx, fetchRequest(url, o))
+		if err != nil {
+			var httpErr *errors.HTTPError
+			if stderrors.As(err, &httpErr) {
+				return nil, err
+			}
+			return nil, errors.New(errors.CodeFetchFailed, err, "failed to fetch: %v", err)
+		}
+		if page == nil {
+			return nil, errors.New(errors.CodeFetchFailed, nil, "failed to fetch: fetcher returned no page")
+		}
+
+		result := &helpers.FetchResult{
+			Body:        page.Body,
+			StatusCode:  page.StatusCode,
+			ContentType: page.Header.Get("Content-Type"),
+			FinalURL:    page.URL,
+			Truncated:   page.Truncated,
+		}
+		if result.FinalURL == "" {
+			result.FinalURL = url
+		}
+		result.BotProtection = helpers.DetectBotProtection(&helpers.BotSignals{
+			StatusCode: page.StatusCode,
+			Header:     page.Header,
+			Cookies:    (&http.Response{Header: page.Header}).Cookies(),
+			Body:       page.Body,
+		})
+		return result, nil
+	}
+}
+
+func renderWith(r Renderer) func(context.Context, string, helpers.FetchOptions) ([]byte, error) {
+	return func(ctx context.Context, url string, o helpers.FetchOptions) ([]byte, error) {
+		return r.Render(ctx, fetchRequest(url, o))
+	}
+}
+
+func renderServer(base string) func(context.Context, string, helpers.FetchOptions) ([]byte, error) {
+	return func(ctx context.Context, url string, o helpers.FetchOptions) ([]byte, error) {
+		o.RenderServer = base
+		return helpers.FetchRenderedDOMContext(ctx, url, o)
+	}
+}
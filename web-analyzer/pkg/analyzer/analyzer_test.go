@@ -0,0 +1,263 @@
+package analyzer
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/pkg/errors"
+)
+
+// staticFetcher serves body for every URL and counts the fetches.
+func staticFetcher(body string, calls *atomic.Int32) Fetcher {
+	return FetcherFunc(func(ctx context.Context, req FetchRequest) (*Page, error) {
+		calls.Add(1)
+		return &Page{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/html"}}, Body: []byte(body)}, nil
+	})
+}
+
+func TestAnalyzer_CustomFetcherAndHeadings(t *testing.T) {
+	// The Analyzer must not depend on the package-level config hook
+	load := analyzer.LoadTagConfig
+	analyzer.LoadTagConfig = func() (*analyzer.TagConfig, error) { return nil, stderrors.New("unused") }
+	defer func() { analyzer.LoadTagConfig = load }()
+
+	var calls atomic.Int32
+	a, err := New(Config{
+		Headings: []string{"H2"},
+		Fetcher:  staticFetcher(`<html><title>Custom</title><h1>One</h1><h2>Two</h2></html>`, &calls),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := a.Analyze(context.Background(), "https://example.com/", Options{SkipLinkCheck: true})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Title != "Custom" || result.FinalURL != "https://example.com/" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(result.Headings) != 1 || result.Headings[0].Tag != "h2" {
+		t.Errorf("Expected only the h2 heading, got %+v", result.Headings)
+	}
+}
+
+func TestAnalyzer_CachesResults(t *testing.T) {
+	var calls atomic.Int32
+	a, _ := New(Config{
+		Fetcher: staticFetcher(`<html><title>Cached</title></html>`, &calls),
+		Cache:   CacheConfig{MaxEntries: 10},
+	})
+	ctx := context.Background()
+	opts := Options{SkipLinkCheck: true}
+
+	for i := 0; i < 2; i++ {
+		if _, err := a.Analyze(ctx, "https://example.com/", opts); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the second analysis from the cache, got %d fetches", calls.Load())
+	}
+
+	opts.Refresh = true
+	a.Analyze(ctx, "https://example.com/", opts)
+	opts.Refresh, opts.Credentials = false, &Credentials{BearerToken: "secret"}
+	a.Analyze(ctx, "https://example.com/", opts)
+	if calls.Load() != 3 {
+		t.Errorf("Expected refreshed and authenticated analyses to fetch, got %d fetches", calls.Load())
+	}
+
+	uncached, _ := New(Config{Fetcher: staticFetcher(`<html></html>`, &calls)})
+	uncached.Analyze(ctx, "https://example.com/", Options{SkipLinkCheck: true})
+	uncached.Analyze(ctx, "https://example.com/", Options{SkipLinkCheck: true})
+	if calls.Load() != 5 {
+		t.Errorf("Expected no caching with the zero CacheConfig, got %d fetches", calls.Load())
+	}
+}
+
+func TestAnalyzer_RendererForRenderAlways(t *testing.T) {
+	var calls atomic.Int32
+	a, _ := New(Config{
+		Fetcher: staticFetcher(`<html><div id="app"></div></html>`, &calls),
+		Renderer: RendererFunc(func(ctx context.Context, req FetchRequest) ([]byte, error) {
+			if req.UserAgent != "test-agent" {
+				t.Errorf("Expected the options' user agent, got %q", req.UserAgent)
+			}
+			return []byte(`<html><title>Rendered</title></html>`), nil
+		}),
+	})
+
+	result, err := a.Analyze(context.Background(), "https://example.com/", Options{
+		RenderMode:    RenderAlways,
+		UserAgent:     "test-agent",
+		SkipLinkCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if !result.Rendered || result.Title != "Rendered" {
+		t.Errorf("Expected the rendered DOM, got %+v", result)
+	}
+}
+
+func TestAnalyzer_ErrorsAreTyped(t *testing.T) {
+	failWith := func(err error) *Analyzer {
+		a, _ := New(Config{Fetcher: FetcherFunc(func(context.Context, FetchRequest) (*Page, error) { return nil, err })})
+		return a
+	}
+	ctx := context.Background()
+
+	_, err := failWith(stderrors.New("boom")).Analyze(ctx, "https://example.com/", Options{})
+	if !stderrors.Is(err, errors.ErrFetchFailed) {
+		t.Errorf("Expected fetch_failed for a plain error, got %v", err)
+	}
+	_, err = failWith(errors.New(errors.CodeDNS, nil, "no such host")).Analyze(ctx, "https://example.com/", Options{})
+	if !stderrors.Is(err, errors.ErrDNS) {
+		t.Errorf("Expected the fetcher's dns_failure to be kept, got %v", err)
+	}
+	_, err = failWith(nil).Analyze(ctx, "https://example.com/", Options{RenderMode: "sometimes"})
+	if !stderrors.Is(err, errors.ErrInvalidOptions) {
+		t.Errorf("Expected invalid_options, got %v", err)
+	}
+}
+
+func TestAnalyzer_DefaultFetcherWithLinkCache(t *testing.T) {
+	var linkChecks atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			linkChecks.Add(1)
+			return
+		}
+		w.Write([]byte(`<html><title>Live</title><a href="/ok">ok</a></html>`))
+	}))
+	defer ts.Close()
+
+	a, _ := New(Config{AllowedHosts: []string{"127.0.0.1"}, LinkCache: LinkCacheConfig{MaxEntries: 10}})
+	for i := 0; i < 2; i++ {
+		result, err := a.Analyze(context.Background(), ts.URL, Options{})
+		if err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		if result.Title != "Live" || len(result.AccessibleLinks) != 1 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	}
+	if linkChecks.Load() != 1 {
+		t.Errorf("Expected the second analysis to reuse the link status, got %d checks", linkChecks.Load())
+	}
+}
+
+func TestAnalyzer_AllowedHostsArePerAnalyzer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><title>Loopback</title></html>`))
+	}))
+	defer ts.Close()
+
+	blocked, _ := New(Config{})
+	allowed, _ := New(Config{AllowedHosts: []string{"127.0.0.1"}})
+	opts := Options{SkipLinkCheck: true}
+
+	if _, err := blocked.Analyze(context.Background(), ts.URL, opts); !stderrors.Is(err, errors.ErrBlockedHost) {
+		t.Errorf("Expected blocked_host without an allowlist, got %v", err)
+	}
+	result, err := allowed.Analyze(context.Background(), ts.URL, opts)
+	if err != nil || result.Title != "Loopback" {
+		t.Errorf("Expected the allowed Analyzer to reach loopback, got %+v, %v", result, err)
+	}
+	if _, err := New(Config{Proxy: "ftp://proxy"}); err == nil {
+		t.Error("Expected an invalid proxy to be rejected")
+	}
+}
+
+func TestAnalyzer_FetchLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.Write([]byte(`<html><title>Long</title><body>` + strings.Repeat("a", 1000) + `</body></html>`))
+	}))
+	defer ts.Close()
+
+	a, _ := New(Config{AllowedHosts: []string{"127.0.0.1"}, MaxBodyBytes: 64, ReadTimeout: 50 * time.Millisecond})
+	opts := Options{SkipLinkCheck: true, RenderMode: RenderNever}
+	result, err := a.Analyze(context.Background(), ts.URL, opts)
+	if err != nil || !result.BodyTruncated {
+		t.Errorf("Expected the fetched page to be cut at MaxBodyBytes, got %+v, %v", result, err)
+	}
+	if _, err := a.Analyze(context.Background(), ts.URL+"/slow", opts); !stderrors.Is(err, errors.ErrTimeout) {
+		t.Errorf("Expected ReadTimeout to bound the fetch, got %v", err)
+	}
+
+	rendered, _ := New(Config{
+		Fetcher: staticFetcher(`<html></html>`, new(atomic.Int32)),
+		Renderer: RendererFunc(func(ctx context.Context, req FetchRequest) ([]byte, error) {
+			return []byte(`<html>` + strings.Repeat("a", 1000) + `</html>`), nil
+		}),
+		MaxBodyBytes: 64,
+	})
+	result, err = rendered.Analyze(context.Background(), "https://example.com/", Options{SkipLinkCheck: true, RenderMode: RenderAlways})
+	if err != nil || !result.BodyTruncated {
+		t.Errorf("Expected the rendered DOM to be cut at MaxBodyBytes, got %+v, %v", result, err)
+	}
+}
+
+// roundTripFunc adapts a function to an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestAnalyzer_LinkClientAndMetrics(t *testing.T) {
+	var calls, linkChecks atomic.Int32
+	a, _ := New(Config{
+		Fetcher: staticFetcher(`<html><a href="https://example.com/ok">ok</a><a href="https://example.com/gone">gone</a></html>`, &calls),
+		LinkClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			linkChecks.Add(1)
+			status := http.StatusOK
+			if r.URL.Path == "/gone" {
+				status = http.StatusNotFound
+			}
+			return &http.Response{StatusCode: status, Body: http.NoBody, Request: r}, nil
+		})},
+	})
+
+	result, err := a.Analyze(context.Background(), "https://example.com/", Options{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if linkChecks.Load() != 2 || len(result.AccessibleLinks) != 1 || len(result.InaccessibleLinks) != 1 {
+		t.Errorf("Expected both links checked through the LinkClient, got %d checks and %+v", linkChecks.Load(), result)
+	}
+
+	var buf strings.Builder
+	if err := a.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	for _, want := range []string{`webanalyzer_link_checks_total{category="2xx"} 1`, `webanalyzer_link_checks_total{category="4xx"} 1`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in the Analyzer's metrics:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestAnalyzer_CustomFetcherNonHTMLIsUnsupported(t *testing.T) {
+	for name, page := range map[string]*Page{
+		"declared": {StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/pdf"}}, Body: []byte("%PDF-1.7")},
+		"sniffed":  {StatusCode: http.StatusOK, Body: []byte("%PDF-1.7\n")},
+	} {
+		a, _ := New(Config{Fetcher: FetcherFunc(func(context.Context, FetchRequest) (*Page, error) { return page, nil })})
+
+		_, err := a.Analyze(context.Background(), "https://example.com/doc", Options{SkipLinkCheck: true})
+		var httpErr *errors.HTTPError
+		if !stderrors.As(err, &httpErr) || httpErr.Code != errors.CodeUnsupportedContent || httpErr.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected 422 unsupported_content, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package api defines the JSON schema of the versioned /api/v1 endpoints.
+// Field names are snake_case and durations are integer milliseconds; fields
+// are only ever added to this version, never renamed or removed.
+package api
+
+import "time"
+
+// Version is the path prefix of the endpoints described here.
+const Version = "/api/v1"
+
+// AnalyzeRequest is the body of POST /api/v1/analyze.
+type AnalyzeRequest struct {
+	URL     string          `json:"url"`
+	Options *AnalyzeOptions `json:"options,omitempty"`
+}
+
+// BatchRequest is the JSON body of POST /api/v1/batch.
+type BatchRequest struct {
+	URLs    []string        `json:"urls"`
+	Options *AnalyzeOptions `json:"options,omitempty"`
+}
+
+// AnalyzeOptions tune one analysis; zero values take the server defaults.
+type AnalyzeOptions struct {
+	UserAgent       string            `json:"user_agent,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	FetchTimeoutMs  int64             `json:"fetch_timeout_ms,omitempty"`
+	RenderTimeoutMs int64             `json:"render_timeout_ms,omitempty"`
+	LinkTimeoutMs   int64             `json:"link_timeout_ms,omitempty"`
+	LinkConcurrency int               `json:"link_concurrency,omitempty"`
+	CheckLinks      *bool             `json:"check_links,omitempty"`
+	RenderMode      string            `json:"render_mode,omitempty"` // auto, always or never
+	Credentials     *Credentials      `json:"credentials,omitempty"`
+	Proxy           string            `json:"proxy,omitempty"`
+	MaxLinks        int               `json:"max_links,omitempty"`
+	MaxDurationMs   int64             `json:"max_duration_ms,omitempty"`
+	MaxDOMNodes     int               `json:"max_dom_nodes,omitempty"`
+}
+
+// Credentials authenticate the analysis against the analyzed site.
+type Credentials struct {
+	Cookies     []Cookie   `json:"cookies,omitempty"`
+	Cookie      string     `json:"cookie,omitempty"`
+	BearerToken string     `json:"bearer_token,omitempty"`
+	BasicAuth   *BasicAuth `json:"basic_auth,omitempty"`
+}
+
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Result is the outcome of one analysis.
+type Result struct {
+	PageURL            string           `json:"page_url"`
+	FinalURL           string           `json:"final_url"`
+	Redirects          []Redirect       `json:"redirects"`
+	ClientRedirects    []ClientRedirect `json:"client_redirects"`
+	HTMLVersion        string           `json:"html_version"`
+	Title              string           `json:"title"`
+	Headings           []Heading        `json:"headings"`
+	InternalLinks      []Link           `json:"internal_links"`
+	ExternalLinks      []Link           `json:"external_links"`
+	AccessibleLinks    []Link           `json:"accessible_links"`
+	InaccessibleLinks  []Link           `json:"inaccessible_links"`
+	HasLoginForm       bool             `json:"has_login_form"`
+	MixedContent       []MixedContent   `json:"mixed_content"`
+	BotProtection      *BotProtection   `json:"bot_protection,omitempty"`
+	Rendered           bool             `json:"rendered"`
+	BodyTruncated      bool             `json:"body_truncated"`
+	Revalidated        bool             `json:"revalidated"`
+	LinkCache          LinkCacheStats   `json:"link_cache"`
+	Partial            bool             `json:"partial"`
+	Skipped            []Skipped        `json:"skipped"`
+	AnalysisDurationMs int64            `json:"analysis_duration_ms"`
+}
+
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+	Permanent  bool   `json:"permanent"`
+}
+
+type ClientRedirect struct {
+	Type    string `json:"type"` // meta-refresh or javascript
+	URL     string `json:"url"`
+	DelayMs int64  `json:"delay_ms"`
+}
+
+type Heading struct {
+	Tag  string `json:"tag"`
+	Text string `json:"text"`
+}
+
+type Link struct {
+	URL         string `json:"url"`
+	Label       string `json:"label"`
+	Occurrences int    `json:"occurrences"`
+}
+
+type MixedContent struct {
+	URL       string `json:"url"`
+	Element   string `json:"element"`
+	Attribute string `json:"attribute"`
+	Type      string `json:"type"` // active or passive
+}
+
+type BotProtection struct {
+	Vendor string `json:"vendor"`
+	Reason string `json:"reason"`
+}
+
+type LinkCacheStats struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// Skipped names a part of the analysis cut short by a budget.
+type Skipped struct {
+	Part   string `json:"part"`
+	Budget string `json:"budget"`
+	Detail string `json:"detail"`
+}
+
+// BatchLine is one line of the NDJSON stream returned by POST /api/v1/batch.
+// Exactly one of Result and Error is set.
+type BatchLine struct {
+	Index  int     `json:"index"`
+	URL    string  `json:"url"`
+	Result *Result `json:"result,omitempty"`
+	Error  *Error  `json:"error,omitempty"`
+}
+
+// Usage is the body of GET /api/v1/usage: the calling key's limits and the
+// requests counted against them.
+type Usage struct {
+	ID         string    `json:"id"`
+	RateLimit  string    `json:"rate_limit,omitempty"`
+	DailyQuota int       `json:"daily_quota,omitempty"`
+	Features   []string  `json:"features"`
+	Day        string    `json:"day"`
+	Today      int       `json:"today"`
+	Total      int64     `json:"total"`
+	Rejected   int64     `json:"rejected"`
+	LastUsed   time.Time `json:"last_used"`
+}
+
+// ErrorResponse is the body of every non-2xx /api/v1 response.
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// Error describes a failure. Code is stable and machine-readable; Message is
+// for humans and may change.
+type Error struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id,omitempty"`
+}
@@ -0,0 +1,289 @@
+// Package client calls the web-analyzer /api/v1 endpoints. Requests that are
+// rate limited (429) or hit an unavailable server (503) are retried, waiting
+// as long as Retry-After asks. Error responses are returned as *APIError,
+// which unwraps to the server's *errors.HTTPError so that
+//
+//	stderrors.Is(err, errors.ErrDNS)
+//
+// works the same on both sides of the wire.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-analyzer/pkg/api"
+	"web-analyzer/pkg/errors"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultMaxRetryWait = time.Minute
+	defaultBackoff      = time.Second // first wait when Retry-After is missing
+)
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL      *url.URL
+	apiKey       string
+	httpClient   *http.Client
+	maxRetries   int
+	maxRetryWait time.Duration
+	userAgent    string
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sends key in the X-API-Key header.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces http.DefaultClient. Its timeout bounds each attempt;
+// use the context to bound a call including retries.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how often a 429 or 503 is retried and the longest
+// Retry-After the client is willing to wait. A longer wait, such as a daily
+// quota that resets at midnight, is returned as an error right away.
+func WithRetries(maxRetries int, maxWait time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.maxRetryWait = maxRetries, maxWait }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client for the server at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q", baseURL)
+	}
+	c := &Client{
+		baseURL:      u,
+		httpClient:   http.DefaultClient,
+		maxRetries:   defaultMaxRetries,
+		maxRetryWait: defaultMaxRetryWait,
+		userAgent:    "web-analyzer-client",
+		sleep:        sleepContext,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// CallOption adjusts a single analyze or batch call.
+type CallOption func(url.Values)
+
+// Refresh ignores cached results and analyzes again.
+func Refresh() CallOption {
+	return func(q url.Values) { q.Set("refresh", "true") }
+}
+
+// RecheckLinks checks the links of a revalidated page again.
+func RecheckLinks() CallOption {
+	return func(q url.Values) { q.Set("recheckLinks", "true") }
+}
+
+// Analyze analyzes one page.
+func (c *Client) Analyze(ctx context.Context, req api.AnalyzeRequest, opts ...CallOption) (*api.Result, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/analyze", req, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result api.Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("client: decoding result: %w", err)
+	}
+	return &result, nil
+}
+
+// Batch analyzes several pages and calls fn with each line as the server
+// streams it, in completion order. A line for a failed URL has Error set; the
+// batch goes on. If fn returns an error the stream is abandoned and that error
+// is returned.
+func (c *Client) Batch(ctx context.Context, req api.BatchRequest, fn func(api.BatchLine) error, opts ...CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, http.MethodPost, "/batch", req, opts)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line api.BatchLine
+		if err := dec.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("client: decoding batch line: %w", err)
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+}
+
+// Usage returns the limits and usage of the client's API key.
+func (c *Client) Usage(ctx context.Context) (*api.Usage, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/usage", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var usage api.Usage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, fmt.Errorf("client: decoding usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// do sends the request, retrying 429 and 503 responses, and returns the first
+// 2xx response. Any other response is decoded into an *APIError.
+func (c *Client) do(ctx context.Context, method, path string, body any, opts []CallOption) (*http.Response, error) {
+	u := c.baseURL.JoinPath(api.Version, path)
+	q := url.Values{}
+	for _, opt := range opts {
+		opt(q)
+	}
+	u.RawQuery = q.Encode()
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("client: encoding request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("client: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("client: %w", err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		apiErr := decodeError(resp)
+		resp.Body.Close()
+		if !retryable(resp.StatusCode) || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+		wait := apiErr.RetryAfter
+		if wait <= 0 {
+			wait = defaultBackoff << attempt
+		}
+		if wait > c.maxRetryWait {
+			return nil, apiErr
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("client: %w", err)
+		}
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// APIError is an error response from the server.
+type APIError struct {
+	StatusCode int
+	Code       string // see the Code* constants in pkg/errors
+	Message    string
+	RequestID  string        // quote it when reporting a problem
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("web-analyzer: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Unwrap exposes the error as the server's *errors.HTTPError, so the
+// sentinels in pkg/errors match it.
+func (e *APIError) Unwrap() error {
+	return &errors.HTTPError{StatusCode: e.StatusCode, Message: e.Message, Code: e.Code}
+}
+
+// decodeError reads the error envelope, falling back to the plain body for
+// responses that did not come from the API, such as a proxy's.
+func decodeError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var envelope api.ErrorResponse
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Error.Code != "" {
+		apiErr.Code, apiErr.Message, apiErr.RequestID = envelope.Error.Code, envelope.Error.Message, envelope.Error.RequestID
+		return apiErr
+	}
+	apiErr.Code = errors.CodeForStatus(resp.StatusCode)
+	apiErr.Message = strings.TrimSpace(string(data))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"web-analyzer/internal/analyzer"
+	"web-analyzer/internal/server"
+	"web-analyzer/internal/testutil"
+	"web-analyzer/pkg/api"
+	"web-analyzer/pkg/errors"
+)
+
+func init() {
+	analyzer.LoadTagConfig = func() (*analyzer.TagConfig, error) {
+		return &analyzer.TagConfig{Headings: []string{"h1", "h2", "h3"}}, nil
+	}
+}
+
+// newAPIServer serves the routes as cmd/webanalyzer wires them, with keys
+// required.
+func newAPIServer(t *testing.T, keys ...server.APIKeyConfig) *httptest.Server {
+	t.Helper()
+	store := server.NewAPIKeyStore()
+	for _, cfg := range keys {
+		if _, err := store.Add(cfg); err != nil {
+			t.Fatalf("Add(%s) failed: %v", cfg.ID, err)
+		}
+	}
+	analyzeLimiter := server.NewRateLimiter(server.RateLimitConfig{Route: "/api/analyze", Rate: 100, Burst: 100})
+	t.Cleanup(analyzeLimiter.Close)
+	batchLimiter := server.NewRateLimiter(server.RateLimitConfig{Route: "/api/batch", Rate: 100, Burst: 100})
+	t.Cleanup(batchLimiter.Close)
+
+	ts := httptest.NewServer(server.NewMux(server.RoutesConfig{
+		AnalyzeLimiter: analyzeLimiter,
+		BatchLimiter:   batchLimiter,
+		Keys:           store,
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newPageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pdf" {
+			w.Header().Set("Content-Type", "application/pdf")
+		}
+		w.Write([]byte("<html><title>Page " + r.URL.Path + "</title><h1>Hi</h1></html>"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func noLinks() *api.AnalyzeOptions {
+	check := false
+	return &api.AnalyzeOptions{CheckLinks: &check}
+}
+
+func TestClient_Analyze(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer(t)
+	ts := newAPIServer(t, server.APIKeyConfig{ID: "a", Key: "key-a"})
+	c, err := New(ts.URL, WithAPIKey("key-a"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := c.Analyze(context.Background(), api.AnalyzeRequest{URL: pages.URL + "/a", Options: noLinks()}, Refresh())
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Title != "Page /a" || len(result.Headings) != 1 || result.Headings[0].Text != "Hi" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	usage, err := c.Usage(context.Background())
+	if err != nil || usage.ID != "a" || usage.Today != 1 {
+		t.Errorf("Unexpected usage %+v: %v", usage, err)
+	}
+}
+
+func TestClient_DecodesErrorEnvelope(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer(t)
+	ts := newAPIServer(t, server.APIKeyConfig{ID: "a", Key: "key-a"})
+
+	c, _ := New(ts.URL, WithAPIKey("key-a"))
+	_, err := c.Analyze(context.Background(), api.AnalyzeRequest{URL: pages.URL + "/pdf", Options: noLinks()})
+	var apiErr *APIError
+	if !stderrors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Code != errors.CodeUnsupportedContent || apiErr.RequestID == "" {
+		t.Errorf("Unexpected error: %+v", apiErr)
+	}
+	if !stderrors.Is(err, errors.ErrUnsupportedContent) {
+		t.Errorf("Expected the pkg/errors sentinel to match %v", err)
+	}
+
+	unauthenticated, _ := New(ts.URL)
+	if _, err := unauthenticated.Usage(context.Background()); !stderrors.As(err, &apiErr) || apiErr.Code != errors.CodeUnauthorized {
+		t.Errorf("Expected unauthorized, got %v", err)
+	}
+}
+
+func TestClient_RetriesHonoringRetryAfter(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer(t)
+	// At 20 requests per second the second request waits ~50ms, which
+	// Retry-After rounds up to a second
+	ts := newAPIServer(t, server.APIKeyConfig{ID: "a", Key: "key-a", RateLimit: "20/s,1"})
+
+	c, _ := New(ts.URL, WithAPIKey("key-a"))
+	var mu sync.Mutex
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		waits = append(waits, d)
+		mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Analyze(context.Background(), api.AnalyzeRequest{URL: pages.URL, Options: noLinks()}); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	if len(waits) != 1 || waits[0] != time.Second {
+		t.Errorf("Expected one retry after the Retry-After of 1s, got %v", waits)
+	}
+}
+
+func TestClient_QuotaBeyondMaxWaitIsNotRetried(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer(t)
+	ts := newAPIServer(t, server.APIKeyConfig{ID: "q", Key: "key-q", DailyQuota: 1})
+
+	c, _ := New(ts.URL, WithAPIKey("key-q"), WithRetries(3, time.Second))
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Errorf("Unexpected retry after %v", d)
+		return nil
+	}
+	req := api.AnalyzeRequest{URL: pages.URL, Options: noLinks()}
+	if _, err := c.Analyze(context.Background(), req); err != nil {
+		t.Fatalf("First request failed: %v", err)
+	}
+	_, err := c.Analyze(context.Background(), req)
+	var apiErr *APIError
+	if !stderrors.As(err, &apiErr) || apiErr.Code != errors.CodeRateLimited || apiErr.RetryAfter <= time.Second {
+		t.Errorf("Expected the quota error with its Retry-After, got %v", err)
+	}
+}
+
+func TestClient_Batch(t *testing.T) {
+	testutil.AllowLoopback(t)
+	pages := newPageServer(t)
+	ts := newAPIServer(t,
+		server.APIKeyConfig{ID: "basic", Key: "basic"},
+		server.APIKeyConfig{ID: "full", Key: "full", Features: []server.Feature{server.FeatureBatch}},
+	)
+	req := api.BatchRequest{URLs: []string{pages.URL + "/a", "://bad", pages.URL + "/b"}, Options: noLinks()}
+
+	c, _ := New(ts.URL, WithAPIKey("full"))
+	lines := make(map[int]api.BatchLine)
+	if err := c.Batch(context.Background(), req, func(line api.BatchLine) error {
+		lines[line.Index] = line
+		return nil
+	}); err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if len(lines) != 3 || lines[0].Result == nil || lines[2].Result == nil || lines[2].Result.Title != "Page /b" {
+		t.Errorf("Unexpected lines: %+v", lines)
+	}
+	if lines[1].Error == nil || lines[1].Error.Code != errors.CodeInvalidURL {
+		t.Errorf("Expected an inline invalid_url error, got %+v", lines[1])
+	}
+
+	stop := stderrors.New("stop")
+	calls := 0
+	err := c.Batch(context.Background(), req, func(api.BatchLine) error { calls++; return stop })
+	if err != stop || calls != 1 {
+		t.Errorf("Expected the callback error after one line, got %v after %d", err, calls)
+	}
+
+	basic, _ := New(ts.URL, WithAPIKey("basic"))
+	if err := basic.Batch(context.Background(), req, func(api.BatchLine) error { return nil }); !stderrors.Is(err, &errors.HTTPError{Code: errors.CodeForbidden}) {
+		t.Errorf("Expected forbidden without the batch feature, got %v", err)
+	}
+}
+
+func TestNew_RejectsInvalidBaseURL(t *testing.T) {
+	for _, base := range []string{"", "localhost:8080", "ftp://x"} {
+		if _, err := New(base); err == nil {
+			t.Errorf("Expected %q to be rejected", base)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("Expected 7s, got %v", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); got < 59*time.Minute {
+		t.Errorf("Expected about an hour, got %v", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("Expected 0, got %v", got)
+	}
+}
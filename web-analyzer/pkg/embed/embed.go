@@ -33,3 +33,8 @@ func LoadEmbeddedConfigFile(filename string) ([]byte, error) {
 	}
 	return data, nil
 }
+
+// OpenAPISpec is the OpenAPI document served at /api/v1/openapi.json.
+//
+//go:embed openapi/openapi.json
+var OpenAPISpec []byte
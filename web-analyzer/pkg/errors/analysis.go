@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"net/http"
+
+	"web-analyzer/internal/constants"
+)
+
+// Codes for failures to fetch, render or analyze a page.
+const (
+	CodeInvalidURL         = "invalid_url"         // the URL cannot be requested
+	CodeInvalidOptions     = "invalid_options"     // an analysis option is out of range
+	CodeBlockedHost        = "blocked_host"        // the SSRF guard refused the address
+	CodeDNS                = "dns_failure"         // the host name did not resolve
+	CodeConnection         = "connection_failed"   // the host refused or dropped the connection
+	CodeTLS                = "tls_failure"         // the TLS handshake or certificate check failed
+	CodeRedirectLoop       = "redirect_loop"       // redirects looped or exceeded the limit
+	CodeFetchFailed        = "fetch_failed"        // any other failure to fetch the page
+	CodeTimeout            = "timeout"             // the page or the analysis deadline timed out
+	CodeUnsupportedContent = "unsupported_content" // the page is not HTML
+	CodeParse              = "parse_failure"       // the page could not be parsed
+	CodeRenderUnavailable  = "render_unavailable"  // the render server failed or is unreachable
+)
+
+// Sentinels for errors.Is; they match any *HTTPError with the same code.
+var (
+	ErrInvalidURL         = &HTTPError{Code: CodeInvalidURL}
+	ErrInvalidOptions     = &HTTPError{Code: CodeInvalidOptions}
+	ErrBlockedHost        = &HTTPError{Code: CodeBlockedHost}
+	ErrDNS                = &HTTPError{Code: CodeDNS}
+	ErrConnection         = &HTTPError{Code: CodeConnection}
+	ErrTLS                = &HTTPError{Code: CodeTLS}
+	ErrRedirectLoop       = &HTTPError{Code: CodeRedirectLoop}
+	ErrFetchFailed        = &HTTPError{Code: CodeFetchFailed}
+	ErrTimeout            = &HTTPError{Code: CodeTimeout}
+	ErrCancelled          = &HTTPError{Code: CodeClientClosedRequest}
+	ErrUnsupportedContent = &HTTPError{Code: CodeUnsupportedContent}
+	ErrParse              = &HTTPError{Code: CodeParse}
+	ErrRenderUnavailable  = &HTTPError{Code: CodeRenderUnavailable}
+	ErrInternal           = &HTTPError{Code: CodeInternal}
+)
+
+// StatusForCode returns the HTTP status a code is reported with. Failures of
+// the analyzed site are 502 (or 504 for timeouts) since the service itself
+// worked; unknown codes are 500.
+func StatusForCode(code string) int {
+	switch code {
+	case CodeInvalidURL, CodeInvalidOptions, CodeBadRequest:
+		return http.StatusBadRequest
+	case CodeBlockedHost, CodeForbidden:
+		return http.StatusForbidden
+	case CodeUnsupportedContent, CodeParse, CodeUnprocessable:
+		return http.StatusUnprocessableEntity
+	case CodeDNS, CodeConnection, CodeTLS, CodeRedirectLoop, CodeFetchFailed, CodeRenderUnavailable, CodeBadGateway:
+		return http.StatusBadGateway
+	case CodeTimeout, CodeGatewayTimeout:
+		return http.StatusGatewayTimeout
+	case CodeClientClosedRequest:
+		return constants.StatusClientClosedRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnsupportedMedia:
+		return http.StatusUnsupportedMediaType
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
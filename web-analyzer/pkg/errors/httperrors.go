@@ -1,10 +1,104 @@
+// Package errors defines the service's error type. An HTTPError carries the
+// HTTP status to answer with, a machine-readable code and the underlying
+// cause, and matches the sentinels in analysis.go with errors.Is:
+//
+//	if stderrors.Is(err, errors.ErrDNS) { ... }
 package errors
 
+import (
+	"fmt"
+	"net/http"
+
+	"web-analyzer/internal/constants"
+)
+
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	Code       string // "" falls back to CodeForStatus(StatusCode)
+	Err        error  // underlying cause, if any
+}
+
+// New returns an error with code, the status that code maps to and a
+// formatted message; cause is kept for errors.Unwrap and may be nil.
+func New(code string, cause error, format string, args ...any) *HTTPError {
+	return &HTTPError{StatusCode: StatusForCode(code), Message: fmt.Sprintf(format, args...), Code: code, Err: cause}
 }
 
 func (e *HTTPError) Error() string {
 	return e.Message
 }
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the machine-readable code of e.
+func (e *HTTPError) ErrorCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return CodeForStatus(e.StatusCode)
+}
+
+// Is reports whether target is an *HTTPError sentinel with the same code, so
+// errors.Is(err, ErrTimeout) matches every timeout however it was worded.
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	return ok && t.Code != "" && t.Code == e.ErrorCode()
+}
+
+// Generic error codes, one per HTTP status, used when an error has no more
+// specific code.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeConflict            = "conflict"
+	CodeUnsupportedMedia    = "unsupported_media_type"
+	CodeUnprocessable       = "unprocessable"
+	CodeRateLimited         = "rate_limited"
+	CodeClientClosedRequest = "client_closed_request"
+	CodeInternal            = "internal"
+	CodeBadGateway          = "bad_gateway"
+	CodeUnavailable         = "unavailable"
+	CodeGatewayTimeout      = "gateway_timeout"
+)
+
+// CodeForStatus returns the generic error code for an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case constants.StatusClientClosedRequest:
+		return CodeClientClosedRequest
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeGatewayTimeout
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}